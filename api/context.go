@@ -0,0 +1,33 @@
+package api
+
+import "context"
+
+type contextKey string
+
+// 请求上下文中可以取到的值，web框架传给Controller的ctx支持这些key
+var (
+	RequestIdKey = contextKey("requestId")
+	TenantIdKey  = contextKey("tenantId")
+)
+
+// 获取ctx中的请求ID
+func RequestIdFrom(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIdKey).(string)
+	return id
+}
+
+// 获取ctx中的用户ID
+func TenantIdFrom(ctx context.Context) string {
+	id, _ := ctx.Value(TenantIdKey).(string)
+	return id
+}
+
+// 在ctx中设置请求ID，用于在非web请求中向下传递
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIdKey, id)
+}
+
+// 在ctx中设置用户ID，用于在非web请求中向下传递
+func WithTenantId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, TenantIdKey, id)
+}
@@ -0,0 +1,392 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/api/restful"
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/controller"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/tools"
+)
+
+const (
+	// 错误响应结构在components中的名称
+	ERROR_SCHEMA = "ErrorResponse"
+	// 普通请求中指定接口和版本的参数
+	PARAM_ACTION  = "Action"
+	PARAM_VERSION = "Version"
+)
+
+// 生成接口文档
+// restful接口按注册的uri生成，其它controller方法按 /rootPath[/model]?Action=xxx&Version=xxx 生成，同时支持GET和POST
+// 请求参数取自方法的第一个入参，响应取自方法第一个非error的返回值
+// models 不为空时只包含其中的模块，与web服务的访问权限一致：restful接口取服务名，其它方法取所在的包名
+func Generate(c controller.Controller, info Info, rootPath string, models []string) *Document {
+	g := &generator{
+		doc: &Document{
+			OpenAPI: VERSION,
+			Info:    info,
+			Paths:   make(map[string]*PathItem),
+		},
+		builder: newSchemaBuilder(),
+	}
+	g.errorResponses()
+
+	// restful接口
+	covered := make(map[*basic.Method]bool)
+	for _, api := range restful.Apis() {
+		for _, version := range api.GetVersions() {
+			method := c.GetController(api.GetServiceName()+"."+api.GetAction(), version)
+			if method == nil {
+				continue
+			}
+			covered[method] = true
+			if !allowed(models, api.GetServiceName()) {
+				continue
+			}
+			g.restfulOperation(api, version, method)
+		}
+	}
+
+	// 普通接口，按key排序保证每次生成的文档一致
+	methods := c.GetAllController()
+	keys := make([]string, 0, len(methods))
+	for k := range methods {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if method := methods[key]; !covered[method] && allowed(models, method.GetPkgName()) {
+			g.actionOperation(key, method, c.UseModel(), rootPath)
+		}
+	}
+
+	g.doc.Components.Schemas = g.builder.schemas
+	return g.doc
+}
+
+// 模块是否在可以访问的模块中，models 为空时不限制
+func allowed(models []string, model string) bool {
+	return len(models) == 0 || tools.ContainsString(models, model)
+}
+
+// restful.Apis 返回的接口描述
+type restfulApi interface {
+	GetServiceName() string
+	GetMethod() string
+	GetAction() string
+	GetUri() string
+	GetVersions() []string
+	GetFields() []string
+	IsIntField(field string) bool
+}
+
+type generator struct {
+	doc     *Document
+	builder *schemaBuilder
+	errors  map[string]*Response // 按HTTP状态码引用的错误响应
+}
+
+// restful接口，路径上的变量作为path参数
+func (g *generator) restfulOperation(api restfulApi, version string, method *basic.Method) {
+	path := "/" + version + api.GetUri()
+	op := &Operation{
+		OperationId: version + "." + api.GetAction(),
+		Tags:        []string{api.GetServiceName()},
+		Responses:   g.responses(method),
+	}
+
+	// 路径参数的类型取自请求结构中的同名字段
+	arg := argType(method)
+	skip := make(map[string]bool)
+	for _, field := range api.GetFields() {
+		skip[field] = true
+		schema := &Schema{Type: "string"}
+		if api.IsIntField(field) {
+			schema = &Schema{Type: "integer", Format: "int64"}
+		} else if f, ok := findField(arg, field); ok && isScalar(f.Type) {
+			schema = g.builder.schemaOf(f.Type)
+		}
+		op.Parameters = append(op.Parameters, &Parameter{Name: field, In: "path", Required: true, Schema: schema})
+	}
+
+	if arg != nil {
+		switch api.GetMethod() {
+		case http.MethodGet, http.MethodDelete:
+			op.Parameters = append(op.Parameters, g.builder.queryParams(arg, "", skip)...)
+		default:
+			op.Parameters = append(op.Parameters, g.builder.headerParams(arg)...)
+			op.RequestBody = g.requestBody(arg)
+		}
+	}
+	g.pathItem(path).set(api.GetMethod(), op)
+}
+
+// 普通接口，路径上带Action和Version参数
+func (g *generator) actionOperation(key string, method *basic.Method, useModel bool, rootPath string) {
+	version := strings.SplitN(key, ".", 2)[0]
+	name := key[strings.LastIndex(key, ".")+1:]
+
+	path := ""
+	if rootPath != "" {
+		path = "/" + strings.Trim(rootPath, "/")
+	}
+	if useModel {
+		path = path + "/" + method.GetPkgName()
+	}
+	if path == "" {
+		path = "/"
+	}
+	path = fmt.Sprintf("%s?%s=%s&%s=%s", path, PARAM_ACTION, name, PARAM_VERSION, version)
+
+	params := []*Parameter{
+		{Name: PARAM_ACTION, In: "query", Required: true, Schema: &Schema{Type: "string", Enum: []interface{}{name}}},
+		{Name: PARAM_VERSION, In: "query", Required: true, Schema: &Schema{Type: "string", Enum: []interface{}{version}}},
+	}
+	get := &Operation{
+		OperationId: "get." + key,
+		Tags:        []string{method.GetPkgName()},
+		Parameters:  params,
+		Responses:   g.responses(method),
+	}
+	post := &Operation{
+		OperationId: "post." + key,
+		Tags:        []string{method.GetPkgName()},
+		Parameters:  params,
+		Responses:   get.Responses,
+	}
+	if arg := argType(method); arg != nil {
+		// 请求结构中的同名字段由url上的Action和Version赋值
+		skip := map[string]bool{PARAM_ACTION: true, PARAM_VERSION: true}
+		get.Parameters = append(append([]*Parameter{}, params...), g.builder.queryParams(arg, "", skip)...)
+		post.Parameters = append(append([]*Parameter{}, params...), g.builder.headerParams(arg)...)
+		post.RequestBody = g.requestBody(arg)
+	}
+
+	item := g.pathItem(path)
+	item.Get, item.Post = get, post
+}
+
+func (g *generator) pathItem(path string) *PathItem {
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+	return item
+}
+
+func (g *generator) requestBody(arg reflect.Type) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: g.builder.schemaOf(arg)}},
+	}
+}
+
+// 成功响应与 web 框架的 successResponse 一致，错误响应引用components中的定义
+func (g *generator) responses(method *basic.Method) map[string]*Response {
+	success := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"requestId": {Type: "string"}},
+	}
+	for _, tp := range method.GetReturn() {
+		if tp.Implements(errorType) {
+			continue
+		}
+		success.Properties["result"] = g.builder.schemaOf(tp)
+		break
+	}
+
+	responses := map[string]*Response{
+		"200": {Description: "OK", Content: map[string]*MediaType{"application/json": {Schema: success}}},
+	}
+	for code := range g.errors {
+		responses[code] = &Response{Ref: "#/components/responses/" + errorResponseName(code)}
+	}
+	return responses
+}
+
+// 按HTTP状态码合并 e.ErrorCode，生成公共的错误响应
+func (g *generator) errorResponses() {
+	types := make([]interface{}, 0)
+	descriptions := make(map[string][]string)
+	for _, code := range e.ErrorCodes() {
+		status := strconv.Itoa(code.Code)
+		descriptions[status] = append(descriptions[status], code.Type)
+		types = append(types, code.Type)
+	}
+
+	// 先占用名称，业务中的同名struct使用带包名的名称
+	g.builder.schemas[ERROR_SCHEMA] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"RequestId": {Type: "string"},
+			"Error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"Code":    {Type: "integer", Format: "int32"},
+					"Status":  {Type: "string", Enum: types},
+					"Message": {Type: "string"},
+					"Details": {Type: "array", Items: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}},
+				},
+			},
+		},
+	}
+
+	g.errors = make(map[string]*Response)
+	g.doc.Components.Responses = make(map[string]*Response)
+	for status, list := range descriptions {
+		resp := &Response{
+			Description: strings.Join(list, ", "),
+			Content:     map[string]*MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/" + ERROR_SCHEMA}}},
+		}
+		g.errors[status] = resp
+		g.doc.Components.Responses[errorResponseName(status)] = resp
+	}
+}
+
+func errorResponseName(status string) string {
+	return "Error" + status
+}
+
+// 方法的请求参数类型，没有参数或参数不是struct时返回nil
+func argType(method *basic.Method) reflect.Type {
+	if len(method.GetArgs()) == 0 {
+		return nil
+	}
+	t := method.GetArgs()[0]
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// GET请求的参数，与 web 框架解析url参数的规则一致：
+// 使用字段名称，嵌套struct的字段为 Parent.Field，数组为 Name.N，匿名struct的字段不带前缀
+// 只有第一层的字段会标记为必填
+func (b *schemaBuilder) queryParams(t reflect.Type, prefix string, skip map[string]bool) []*Parameter {
+	params := make([]*Parameter, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Func, reflect.Chan, reflect.Map, reflect.UnsafePointer:
+			continue
+		}
+
+		if field.Anonymous && ft.Kind() == reflect.Struct {
+			params = append(params, b.queryParams(ft, prefix, skip)...)
+			continue
+		}
+
+		// 从请求头和cookie中取值的字段
+		if in, key := headerIn(field); in != "" {
+			params = append(params, &Parameter{Name: key, In: in, Schema: b.schemaOf(ft)})
+			continue
+		}
+
+		name := paramName(prefix, field.Name)
+		if skip[name] {
+			continue
+		}
+
+		switch {
+		case isScalar(ft):
+			schema := b.schemaOf(ft)
+			required := applyRules(schema, field) && prefix == ""
+			params = append(params, &Parameter{Name: name, In: "query", Required: required, Schema: schema})
+
+		case ft.Kind() == reflect.Struct:
+			params = append(params, b.queryParams(ft, name, skip)...)
+
+		case ft.Kind() == reflect.Slice && !isScalar(ft.Elem()):
+			elem := ft.Elem()
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct {
+				params = append(params, b.queryParams(elem, name+".N", skip)...)
+			}
+
+		case ft.Kind() == reflect.Slice:
+			schema := b.schemaOf(ft)
+			required := applyRules(schema, field) && prefix == ""
+			desc := "N starts from 1"
+			if schema.MaxItems != nil {
+				desc = fmt.Sprintf("%s, at most %d items", desc, *schema.MaxItems)
+			}
+			if schema.Items == nil {
+				// []byte 按一个字符串参数传递
+				params = append(params, &Parameter{Name: name, In: "query", Required: required, Schema: schema})
+				continue
+			}
+			params = append(params, &Parameter{Name: name + ".N", In: "query", Description: desc, Required: required, Schema: schema.Items})
+		}
+	}
+	return params
+}
+
+// 请求头和cookie中的参数，json body的请求中同样生效
+func (b *schemaBuilder) headerParams(t reflect.Type) []*Parameter {
+	params := make([]*Parameter, 0)
+	for _, p := range b.queryParams(t, "", nil) {
+		if p.In != "query" {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// 字段上的 header、cookie 标签，与 web 框架一致
+func headerIn(field reflect.StructField) (string, string) {
+	if key := field.Tag.Get("header"); key != "" {
+		return "header", key
+	}
+	if key := field.Tag.Get("cookie"); key != "" {
+		return "cookie", key
+	}
+	return "", ""
+}
+
+// 按json的规则查找字段，匿名struct中的字段可以直接匹配，与 web 框架赋值路径参数的规则一致
+func findField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t == nil {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			if f, ok := findField(ft, name); ok {
+				return f, true
+			}
+			continue
+		}
+		if strings.EqualFold(key, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Zoxu0928/task-common/api"
+	"github.com/Zoxu0928/task-common/api/restful"
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/controller"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/stretchr/testify/assert"
+)
+
+type Tag struct {
+	Key   string `verf:"" maxLen:"32"`
+	Value string `verf:"" nilable:"" maxLen:"64"`
+}
+
+type Thing struct {
+	Id        string
+	CreatedAt basic.TimeStandard
+}
+
+type DescribeThingsRequest struct {
+	api.Request
+	Name     string   `verf:"" minLen:"1" maxLen:"10" reg:"^[a-z]+$"`
+	Status   string   `verf:"" nilable:"" inList:"running,stopped"`
+	PageSize int      `verf:"" nilable:"" biggerEq:"1" lowerEq:"100"`
+	Ids      []string `verf:"" nilable:"" maxLen:"5" non-repeatable:""`
+	Tags     []*Tag
+	Day      basic.Day
+}
+
+type CreateThingRequest struct {
+	api.Request
+	ThingId int64
+	Count   int `verf:"" bigger:"0" lower:"10"`
+}
+
+func DescribeThings(req *DescribeThingsRequest) ([]*Thing, e.ApiError) {
+	return nil, nil
+}
+
+func CreateThing(req *CreateThingRequest) (*Thing, e.ApiError) {
+	return nil, nil
+}
+
+func TestGenerate(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(DescribeThings)
+	c.AddController(CreateThing)
+	// controller的版本取自包路径的上一级
+	restful.RegisterApi(restful.NewRestful().ServiceName("demo").Method(restful.POST).Uri("/things/{thingId}").SupportVersion("api").Action("CreateThing"))
+
+	doc := Generate(c, Info{Title: "demo", Version: "v1"}, "api", nil)
+	_, err := json.Marshal(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, VERSION, doc.OpenAPI)
+
+	// restful接口
+	create := doc.Paths["/api/things/{thingId}"]
+	if assert.NotNil(t, create) && assert.NotNil(t, create.Post) {
+		assert.Equal(t, "path", create.Post.Parameters[0].In)
+		assert.Equal(t, "thingId", create.Post.Parameters[0].Name)
+		assert.Equal(t, "integer", create.Post.Parameters[0].Schema.Type)
+		assert.Equal(t, "#/components/schemas/CreateThingRequest", create.Post.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, "#/components/schemas/Thing", create.Post.Responses["200"].Content["application/json"].Schema.Properties["result"].Ref)
+		assert.Equal(t, "#/components/responses/Error400", create.Post.Responses["400"].Ref)
+	}
+	// 已经生成为restful接口的方法不再按Action生成
+	assert.Nil(t, doc.Paths["/api?Action=CreateThing&Version=api"])
+
+	// 请求结构，包含匿名的 api.Request
+	req := doc.Components.Schemas["CreateThingRequest"]
+	if assert.NotNil(t, req) {
+		assert.Contains(t, req.Properties, "RequestId")
+		assert.NotContains(t, req.Properties, "GetHttpContext")
+		assert.Equal(t, []string{"Count"}, req.Required)
+		count := req.Properties["Count"]
+		assert.Equal(t, 0.0, *count.Minimum)
+		assert.True(t, count.ExclusiveMinimum)
+		assert.Equal(t, 10.0, *count.Maximum)
+		assert.True(t, count.ExclusiveMaximum)
+	}
+	assert.Equal(t, "date-time", doc.Components.Schemas["Thing"].Properties["CreatedAt"].Format)
+
+	// 普通接口
+	describe := doc.Paths["/api?Action=DescribeThings&Version=api"]
+	if assert.NotNil(t, describe) && assert.NotNil(t, describe.Get) && assert.NotNil(t, describe.Post) {
+		params := make(map[string]*Parameter)
+		for _, p := range describe.Get.Parameters {
+			params[p.Name] = p
+		}
+		assert.True(t, params["Action"].Required)
+		assert.Contains(t, params, "RequestId")
+		assert.Equal(t, "header", params["X-Jcloud-Pin"].In)
+		assert.NotContains(t, params, "Account")
+
+		name := params["Name"]
+		assert.True(t, name.Required)
+		assert.Equal(t, 1, *name.Schema.MinLength)
+		assert.Equal(t, 10, *name.Schema.MaxLength)
+		assert.Equal(t, "^[a-z]+$", name.Schema.Pattern)
+
+		assert.False(t, params["Status"].Required)
+		assert.Equal(t, []interface{}{"running", "stopped"}, params["Status"].Schema.Enum)
+		assert.Equal(t, 1.0, *params["PageSize"].Schema.Minimum)
+		assert.Equal(t, 100.0, *params["PageSize"].Schema.Maximum)
+		assert.Contains(t, params, "Ids.N")
+		assert.Contains(t, params, "Tags.N.Key")
+		assert.Equal(t, "date", params["Day"].Schema.Format)
+
+		body := doc.Components.Schemas["DescribeThingsRequest"]
+		ids := body.Properties["Ids"]
+		assert.Equal(t, 5, *ids.MaxItems)
+		assert.True(t, ids.UniqueItems)
+		assert.Equal(t, "#/components/schemas/Tag", body.Properties["Tags"].Items.Ref)
+		assert.Equal(t, "array", describe.Post.Responses["200"].Content["application/json"].Schema.Properties["result"].Type)
+	}
+
+	// 错误响应按HTTP状态码合并
+	assert.Contains(t, doc.Components.Responses["Error404"].Description, "NOT_FOUND")
+	assert.Contains(t, doc.Components.Schemas, ERROR_SCHEMA)
+}
+
+func TestGenerate_Models(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(DescribeThings)
+
+	// 只包含可以访问的模块，普通接口的模块取所在的包名
+	doc := Generate(c, Info{Title: "demo", Version: "v1"}, "api", []string{"other"})
+	assert.Nil(t, doc.Paths["/api?Action=DescribeThings&Version=api"])
+	doc = Generate(c, Info{Title: "demo", Version: "v1"}, "api", []string{"other", "openapi"})
+	assert.NotNil(t, doc.Paths["/api?Action=DescribeThings&Version=api"])
+}
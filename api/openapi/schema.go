@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/validator"
+)
+
+// 按时间格式序列化的类型
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	timeStandardType = reflect.TypeOf(basic.TimeStandard{})
+	timeMsType       = reflect.TypeOf(basic.TimeMs{})
+	dayType          = reflect.TypeOf(basic.Day{})
+	durationType     = reflect.TypeOf(basic.Duration{})
+	fileHeaderType   = reflect.TypeOf(multipart.FileHeader{})
+	errorType        = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// 由Go类型生成Schema，具名struct放入components中并返回引用
+type schemaBuilder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// 类型对应的Schema，每次返回新的对象，调用方可以修改
+func (b *schemaBuilder) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType, timeStandardType, timeMsType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case dayType:
+		return &Schema{Type: "string", Format: "date"}
+	case durationType:
+		return &Schema{Type: "string", Description: "duration, e.g. 1h30m"}
+	case fileHeaderType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + b.define(t)}
+	default:
+		// interface{} 等任意类型
+		return &Schema{}
+	}
+}
+
+// 将具名struct放入components，返回名称
+// 不同包中的同名struct使用 包名_类型名 区分
+func (b *schemaBuilder) define(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, ok := b.schemas[name]; ok {
+		name = strings.Replace(t.String(), ".", "_", -1)
+	}
+	b.names[t] = name
+	// 先占位，避免递归引用时重复生成
+	b.schemas[name] = &Schema{}
+	*b.schemas[name] = *b.structSchema(t)
+	return name
+}
+
+// struct的Schema，匿名字段的属性合并到当前struct中
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(t, s)
+	return s
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		// 匿名struct，与json序列化一致，属性合并到当前struct中
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			b.addFields(ft, s)
+			continue
+		}
+
+		fs := b.schemaOf(field.Type)
+		if required := applyRules(fs, field); required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// 字段在json中的名称，忽略的字段返回false
+func jsonName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false
+	}
+	switch field.Type.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// 将字段上的validator规则转换为Schema的约束，返回字段是否必填
+// 只有带 verf 标签的字段才会校验，与 validator 保持一致
+func applyRules(s *Schema, field reflect.StructField) bool {
+	rule := validator.FieldRule(field)
+	if rule == nil {
+		return false
+	}
+	// 引用其它Schema时不能增加约束，只处理是否必填
+	if s.Ref != "" {
+		return !rule.Nilable
+	}
+	return rule.ApplySchema(s)
+}
+
+// 是否是按单个值传递的类型，GET请求中可以作为一个参数
+func isScalar(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType, timeStandardType, timeMsType, dayType, durationType, fileHeaderType:
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Func, reflect.Chan:
+		return false
+	}
+	return true
+}
+
+// 参数名称，与web框架解析GET参数的规则一致：嵌套字段用"."连接，数组元素用 .N 表示
+func paramName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", prefix, name)
+}
@@ -9,8 +9,8 @@ import (
 type Request struct {
 	// 每个请求对应唯一的ID
 	RequestId string
-	// 用戶
-	Account string
+	// 用戶，网关在Header中传过来
+	Account string `header:"X-Jcloud-Pin"`
 	// 子帐户
 	User string
 	// 地域，具体校验从配置中取
@@ -26,9 +26,9 @@ type Request struct {
 	// 请求来源
 	BusiCode string `json:",omitempty"`
 	// 运营后台请求帐号
-	ErpAccount string `json:",omitempty"`
+	ErpAccount string `json:",omitempty" header:"X-Jcloud-Erp"`
 	// 角色，一般如果调用方是代入角色替用户操作的，网关会将角色在Header中传过来
-	Role string `json:",omitempty"`
+	Role string `json:",omitempty" header:"X-Jcloud-Role"`
 	// 用户ID，在用户验证拦截器中设置
 	tenant_id string
 }
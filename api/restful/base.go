@@ -1,11 +1,9 @@
 package restful
 
-import (
-	"regexp"
-)
-
 // restful协议通用属性封装
 
+import "time"
+
 // 自定义类型
 type protocol string
 type service string
@@ -35,9 +33,11 @@ type restfulMethod struct {
 	_versions    []string               // 支持的版本
 	_action      string                 // 接口名称
 	_uri         string                 // 接口匹配路径
-	_uriReg      string                 // 接口匹配路径正则规则
+	_segments    []segment              // 接口匹配路径拆分后的路径段
+	_uriErr      error                  // 接口匹配路径解析失败的原因，注册时报错
 	_fields      []string               // url路径上的参数占位，如/regions/{regionId}/instances/{instanceId}，得到的结果是 ["regionId","instanceId"]
 	_intFields   map[string]interface{} // 数字类型字段
+	_timeout     time.Duration          // 接口执行超时时间，为0时不限制
 }
 
 func (this *restfulMethod) ServiceName(serviceName service) *restfulMethod {
@@ -55,6 +55,13 @@ func (this *restfulMethod) Action(action string) *restfulMethod {
 	return this
 }
 
+// 设置接口执行的超时时间，超时后返回 DEADLINE_EXCEEDED 错误
+// 接口需要接收 context.Context 入参并在取消后尽快返回，否则超时后仍会继续执行
+func (this *restfulMethod) Timeout(timeout time.Duration) *restfulMethod {
+	this._timeout = timeout
+	return this
+}
+
 func (this *restfulMethod) IntFields(fields ...string) *restfulMethod {
 	for _, f := range fields {
 		this._intFields[f] = struct{}{}
@@ -63,29 +70,20 @@ func (this *restfulMethod) IntFields(fields ...string) *restfulMethod {
 }
 
 // 设置uri
-// 取出urk中的替换字段，同时设置uri正则路径
+// 取出uri中的替换字段，同时将uri拆分为路由树的路径段
+// pathFields 为可以匹配带"/"路径的字段，如 Uri("/files/{path}", "{path}")
 func (this *restfulMethod) Uri(uri string, pathFields ...string) *restfulMethod {
 
 	// 从路径中取出所有变量字段
 	this._uri = uri
-	pattern := regexp.MustCompile(FieldReg)
-	parts := pattern.FindAllStringSubmatch(uri, -1)
+	parts := fieldReg.FindAllStringSubmatch(uri, -1)
 	this._fields = make([]string, 0)
 	for _, v := range parts {
 		if len(v) > 1 {
 			this._fields = append(this._fields, v[1])
 		}
 	}
-	// 将路径中的所有变量字段，替换为匹配Value的正则表达式
-	this._uriReg = pattern.ReplaceAllStringFunc(uri, func(field string) string {
-		for _, pathField := range pathFields {
-			if pathField == field {
-				return PathValueReg
-			}
-		}
-		return ValueReg
-	})
-	this._uriReg += "(?:$|[?]|/$|/[?])"
+	this._segments, this._uriErr = parseSegments(uri, pathFields)
 	return this
 }
 
@@ -100,6 +98,32 @@ func (this *restfulMethod) SupportVersion(versions ...string) *restfulMethod {
 	return this
 }
 
+func (this *restfulMethod) GetServiceName() string {
+	return string(this._serviceName)
+}
+func (this *restfulMethod) GetMethod() string {
+	return string(this._method)
+}
+func (this *restfulMethod) GetAction() string {
+	return this._action
+}
+func (this *restfulMethod) GetUri() string {
+	return this._uri
+}
+func (this *restfulMethod) GetVersions() []string {
+	return this._versions
+}
+func (this *restfulMethod) GetFields() []string {
+	return this._fields
+}
+func (this *restfulMethod) GetTimeout() time.Duration {
+	return this._timeout
+}
+func (this *restfulMethod) IsIntField(field string) bool {
+	_, ok := this._intFields[field]
+	return ok
+}
+
 // 判断是否支持此版本
 func (this *restfulMethod) contains(version string) bool {
 	for _, v := range this._versions {
@@ -1,20 +1,54 @@
 package restful
 
 import (
+	"fmt"
+	"github.com/Zoxu0928/task-common/e"
 	"github.com/Zoxu0928/task-common/tools"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
-// 所有接口列表
-var apis = make([]*restfulMethod, 0)
+const (
+	// 错误详情中支持的请求方式
+	K_DETAIL_ALLOW = "allow"
+	// 错误详情中转换失败的路径参数名称
+	K_DETAIL_FIELD = "field"
+)
+
+// 所有接口的路由树，以及按注册顺序排列的接口列表
+var (
+	lck  sync.RWMutex
+	root = newNode(segment{})
+	apis = make([]*restfulMethod, 0)
+)
+
+var restfulReg = regexp.MustCompile(`^/v[0-9]+/`)
 
 // 增加注册一个接口
+// 接口的uri不合法，或与已注册的接口路径、请求方式相同并且版本有交集时panic
 func RegisterApi(method *restfulMethod) {
+	if method._uriErr != nil {
+		panic(method._uriErr)
+	}
+	lck.Lock()
+	defer lck.Unlock()
+	if err := root.insert(method); err != nil {
+		panic(err)
+	}
 	apis = append(apis, method)
 }
 
+// 获取所有已注册的接口，按注册顺序排列
+func Apis() []*restfulMethod {
+	lck.RLock()
+	defer lck.RUnlock()
+	return append([]*restfulMethod{}, apis...)
+}
+
 // restful url分解后的模型
 type action struct {
 	method      string // GET、POST
@@ -26,7 +60,8 @@ type action struct {
 // restful 具体请求的内容，包括参数名称和值
 type ActionMeta struct {
 	action
-	params map[string]interface{} // url上的其它参数的key-value列表
+	params  map[string]interface{} // url上的其它参数的key-value列表
+	timeout time.Duration          // 接口执行超时时间
 }
 
 func (this *ActionMeta) GetMethod() string {
@@ -41,6 +76,9 @@ func (this *ActionMeta) GetVersion() string {
 func (this *ActionMeta) GetName() string {
 	return this.name
 }
+func (this *ActionMeta) GetTimeout() time.Duration {
+	return this.timeout
+}
 func (this *ActionMeta) GetParams() map[string]interface{} {
 	return this.params
 }
@@ -51,55 +89,95 @@ func (this *ActionMeta) PutParam(key string, value interface{}) {
 	this.params[key] = value
 }
 
-// 根据url匹配api接口
+// 根据url匹配api接口，没有匹配的接口时返回nil
 func Match(method, path string) *ActionMeta {
+	a, _ := Route(method, path)
+	return a
+}
 
-	// 拆分为版本和请求路径
-	request_version := getVersion(path)
-	request_path := getPath(path)
-
-	// 遍历所有api
-	for _, _api := range apis {
-
-		// 如果api支持此方式
-		if string(_api._method) == method {
-
-			// 匹配请求路径
-			pattern := regexp.MustCompile(_api._uriReg)
-			parts := pattern.FindStringSubmatch(request_path)
-
-			// 如果匹配成功
-			if len(parts) > 0 {
+// 根据url匹配api接口
+// 路径不存在或版本不支持时返回 NOT_FOUND 错误
+// 路径存在但不支持此请求方式时返回 METHOD_NOT_ALLOWED 错误，错误详情的 allow 为支持的请求方式
+// IntFields 指定的路径参数不是数字时返回 INVALID_ARGUMENT 错误，错误详情的 field 为参数名称
+func Route(method, path string) (*ActionMeta, e.ApiError) {
+
+	// 去掉url参数，拆分为版本和请求路径
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return nil, e.NewApiError(e.NOT_FOUND, "No such api.", nil)
+	}
+	request_version := parts[0]
+
+	lck.RLock()
+	defer lck.RUnlock()
+
+	var a *ActionMeta
+	var paramErr e.ApiError
+	allowed := make(map[string]struct{})
+	root.match(parts[1:], nil, func(n *node, values []string) bool {
+		for _, _api := range n.apis {
+			// 路径匹配但不支持此版本时，不记录为支持的请求方式
+			if !_api.contains(request_version) {
+				continue
+			}
+			if string(_api._method) != method {
+				allowed[string(_api._method)] = struct{}{}
+				continue
+			}
+			a, paramErr = newActionMeta(_api, request_version, values)
+			return true
+		}
+		return false
+	})
+	if paramErr != nil {
+		return nil, paramErr
+	}
+	if a != nil {
+		return a, nil
+	}
+	if len(allowed) > 0 {
+		methods := make([]string, 0, len(allowed))
+		for m := range allowed {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		err := e.NewApiError(e.METHOD_NOT_ALLOWED, fmt.Sprintf("Method %s not allowed.", method), nil)
+		err.AddDetail(K_DETAIL_ALLOW, strings.Join(methods, ", "))
+		return nil, err
+	}
+	return nil, e.NewApiError(e.NOT_FOUND, "No such api.", nil)
+}
 
-				// 如果不支持此版本
-				if !_api.contains(request_version) {
-					continue
-				}
-				a := &ActionMeta{}
-				a.method = string(_api._method)
-				a.serviceName = string(_api._serviceName)
-				a.version = request_version
-				a.name = _api._action
-				a.params = make(map[string]interface{})
-				// 封装url上需要替换的变量
-				for i := 1; i < len(parts); i++ {
-					fieldName := getvalue(_api._fields, i-1)
-					if fieldName != "" {
-						if fieldValue := getvalue(parts, i); fieldValue != "" {
-							if _, ok := _api._intFields[fieldName]; ok {
-								intVal, _ := tools.ToInt(fieldValue)
-								a.params[fieldName] = intVal
-							} else {
-								a.params[fieldName] = fieldValue
-							}
-						}
-					}
+// 封装url上需要替换的变量
+// IntFields 指定的数字类型字段转换失败时返回 INVALID_ARGUMENT，其它字段按请求结构中的字段类型转换
+func newActionMeta(_api *restfulMethod, version string, values []string) (*ActionMeta, e.ApiError) {
+	a := &ActionMeta{}
+	a.method = string(_api._method)
+	a.serviceName = string(_api._serviceName)
+	a.version = version
+	a.name = _api._action
+	a.timeout = _api._timeout
+	a.params = make(map[string]interface{})
+	for i, fieldValue := range values {
+		fieldName := getvalue(_api._fields, i)
+		if fieldName != "" && fieldValue != "" {
+			if _, ok := _api._intFields[fieldName]; ok {
+				intVal, err := tools.ToInt(fieldValue)
+				if err != nil {
+					apiErr := e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", fieldName, fieldValue), err)
+					apiErr.AddDetail(K_DETAIL_FIELD, fieldName)
+					return nil, apiErr
 				}
-				return a
+				a.params[fieldName] = intVal
+			} else {
+				a.params[fieldName] = fieldValue
 			}
 		}
 	}
-	return nil
+	return a, nil
 }
 
 // 根据下标获取数组值
@@ -110,30 +188,7 @@ func getvalue(list []string, index int) string {
 	return ""
 }
 
-// 拆分path获得version
-func getVersion(path string) string {
-	if len(path) < 1 {
-		return ""
-	}
-	if strings.Index(path, "/") == 0 {
-		path = path[1:]
-	}
-	return path[0:strings.Index(path, "/")]
-}
-
-// 拆分path获得version以后的路径
-func getPath(path string) string {
-	if len(path) < 1 {
-		return ""
-	}
-	if strings.Index(path, "/") == 0 {
-		path = path[1:]
-	}
-	return path[strings.Index(path, "/"):]
-}
-
 // 检查请求是否是restful方式
 func IsRestfulRequest(request *http.Request) bool {
-	pattern := regexp.MustCompile(`^/v[0-9]+/`)
-	return pattern.Match([]byte(request.RequestURI))
+	return restfulReg.MatchString(request.RequestURI)
 }
@@ -0,0 +1,105 @@
+package restful
+
+import (
+	"testing"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试使用独立的路由树
+func resetRouter() {
+	root = newNode(segment{})
+	apis = make([]*restfulMethod, 0)
+}
+
+func TestRoute(t *testing.T) {
+	resetRouter()
+	RegisterApi(NewRestful().ServiceName("vm").Method(GET).Uri("/regions/{regionId}/instances").SupportVersion("v1").Action("DescribeInstances"))
+	RegisterApi(NewRestful().ServiceName("vm").Method(GET).Uri("/regions/{regionId}/instances/{instanceId}").SupportVersion("v1", "v2").Action("DescribeInstance"))
+	RegisterApi(NewRestful().ServiceName("vm").Method(DELETE).Uri("/regions/{regionId}/instances/{id}").SupportVersion("v1").Action("DeleteInstance"))
+	RegisterApi(NewRestful().ServiceName("vm").Method(GET).Uri("/regions/{regionId}/instances/count").SupportVersion("v1").Action("CountInstances"))
+	RegisterApi(NewRestful().ServiceName("vm").Method(GET).Uri("/regions/{regionId}/instances/{instanceId}:start").SupportVersion("v1").Action("StartInstance"))
+	RegisterApi(NewRestful().ServiceName("oss").Method(GET).Uri("/buckets/{bucket}/objects/{key}", "{key}").SupportVersion("v1").Action("GetObject"))
+	RegisterApi(NewRestful().ServiceName("oss").Method(GET).Uri("/buckets/{bucket}/objects/{key}/acl", "key").SupportVersion("v1").Action("GetObjectAcl"))
+	RegisterApi(NewRestful().ServiceName("vm").Method(GET).Uri("/pages/{page}").IntFields("page").SupportVersion("v1").Action("Page"))
+
+	a, err := Route("GET", "/v1/regions/cn-north-1/instances?pageSize=10")
+	assert.Nil(t, err)
+	assert.Equal(t, "DescribeInstances", a.GetName())
+	assert.Equal(t, "vm", a.GetServiceName())
+	assert.Equal(t, map[string]interface{}{"regionId": "cn-north-1"}, a.GetParams())
+
+	a, err = Route("GET", "/v2/regions/cn-north-1/instances/i-1/")
+	assert.Nil(t, err)
+	assert.Equal(t, "DescribeInstance", a.GetName())
+	assert.Equal(t, "v2", a.GetVersion())
+	assert.Equal(t, map[string]interface{}{"regionId": "cn-north-1", "instanceId": "i-1"}, a.GetParams())
+
+	// 不同请求方式使用不同的变量名称
+	a, _ = Route("DELETE", "/v1/regions/cn-north-1/instances/i-1")
+	assert.Equal(t, "DeleteInstance", a.GetName())
+	assert.Equal(t, map[string]interface{}{"regionId": "cn-north-1", "id": "i-1"}, a.GetParams())
+
+	// 静态段优先于变量，与注册顺序无关
+	a, _ = Route("GET", "/v1/regions/cn-north-1/instances/count")
+	assert.Equal(t, "CountInstances", a.GetName())
+
+	// 段内变量
+	a, _ = Route("GET", "/v1/regions/cn-north-1/instances/i-1:start")
+	assert.Equal(t, "StartInstance", a.GetName())
+	assert.Equal(t, "i-1", a.GetParams()["instanceId"])
+
+	// 路径变量
+	a, _ = Route("GET", "/v1/buckets/b1/objects/dir/sub/file.txt")
+	assert.Equal(t, "GetObject", a.GetName())
+	assert.Equal(t, "dir/sub/file.txt", a.GetParams()["key"])
+	a, _ = Route("GET", "/v1/buckets/b1/objects/dir/file.txt/acl")
+	assert.Equal(t, "GetObjectAcl", a.GetName())
+	assert.Equal(t, "dir/file.txt", a.GetParams()["key"])
+
+	// 数字类型字段
+	a, _ = Route("GET", "/v1/pages/3")
+	assert.Equal(t, 3, a.GetParams()["page"])
+	_, err = Route("GET", "/v1/pages/abc")
+	assert.Equal(t, e.INVALID_ARGUMENT.Code, err.GetCode())
+	assert.Equal(t, []map[string]string{{K_DETAIL_FIELD: "page"}}, err.GetDetails())
+
+	// 版本不支持
+	_, err = Route("DELETE", "/v2/regions/cn-north-1/instances/i-1")
+	assert.Equal(t, e.METHOD_NOT_ALLOWED.Code, err.GetCode())
+	_, err = Route("GET", "/v3/regions/cn-north-1/instances/i-1")
+	assert.Equal(t, e.NOT_FOUND.Code, err.GetCode())
+
+	// 路径不存在
+	_, err = Route("GET", "/v1/regions/cn-north-1/volumes")
+	assert.Equal(t, e.NOT_FOUND.Code, err.GetCode())
+	assert.Nil(t, Match("GET", "/v1/instances/i-1"))
+
+	// 请求方式不支持
+	_, err = Route("POST", "/v1/regions/cn-north-1/instances/i-1")
+	assert.Equal(t, e.METHOD_NOT_ALLOWED.Code, err.GetCode())
+	assert.Equal(t, "DELETE, GET", err.GetDetails()[0][K_DETAIL_ALLOW])
+}
+
+func TestRegisterApi_Conflict(t *testing.T) {
+	resetRouter()
+	RegisterApi(NewRestful().Method(GET).Uri("/instances/{instanceId}").SupportVersion("v1", "v2").Action("DescribeInstance"))
+
+	// 变量名称不同，路径相同
+	assert.Panics(t, func() {
+		RegisterApi(NewRestful().Method(GET).Uri("/instances/{id}").SupportVersion("v2").Action("GetInstance"))
+	})
+	// 版本没有交集
+	assert.NotPanics(t, func() {
+		RegisterApi(NewRestful().Method(GET).Uri("/instances/{id}").SupportVersion("v3").Action("GetInstance"))
+	})
+	// 请求方式不同
+	assert.NotPanics(t, func() {
+		RegisterApi(NewRestful().Method(PUT).Uri("/instances/{id}").SupportVersion("v1").Action("ModifyInstance"))
+	})
+	// uri不合法
+	assert.Panics(t, func() {
+		RegisterApi(NewRestful().Method(GET).Uri("/instances/{a}{b}").SupportVersion("v1").Action("Bad"))
+	})
+}
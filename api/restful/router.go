@@ -0,0 +1,215 @@
+package restful
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// restful接口的路由树
+// 注册时将uri按"/"拆分为路径段，逐段插入树中；匹配时逐段查找，不再为每个请求编译和遍历正则
+// 同一层的子节点按以下优先级匹配，与注册顺序无关：
+//  1. 静态段，例如 /instances
+//  2. 段内包含变量，例如 /{name}.json
+//  3. 整段为变量，例如 /{instanceId}
+//  4. 路径变量（pathFields），可以匹配包含"/"的多段路径，例如 /{path}
+
+var (
+	valueReg     = regexp.MustCompile("^" + ValueReg + "$")
+	pathValueReg = regexp.MustCompile("^" + PathValueReg + "$")
+	fieldReg     = regexp.MustCompile(FieldReg)
+)
+
+// 路径段的类型，同时也是匹配的优先级
+type segmentKind int
+
+const (
+	segmentStatic  segmentKind = iota // 静态段
+	segmentPattern                    // 段内包含变量
+	segmentParam                      // 整段为变量
+	segmentPath                       // 路径变量，可以匹配多段
+)
+
+// uri拆分后的一个路径段
+type segment struct {
+	kind    segmentKind
+	key     string         // 静态段为内容本身，段内变量为去掉变量名后的模板，用于判断是否是同一个节点
+	pattern *regexp.Regexp // 段内包含变量时的匹配规则
+}
+
+// 路由树的节点
+type node struct {
+	static   map[string]*node
+	patterns []*node
+	param    *node
+	path     *node
+
+	seg  segment
+	apis []*restfulMethod // 路径在此结束的接口
+}
+
+func newNode(seg segment) *node {
+	return &node{seg: seg, static: make(map[string]*node)}
+}
+
+// 获取或创建子节点
+func (n *node) child(seg segment) *node {
+	switch seg.kind {
+	case segmentStatic:
+		if c, ok := n.static[seg.key]; ok {
+			return c
+		}
+		c := newNode(seg)
+		n.static[seg.key] = c
+		return c
+	case segmentPattern:
+		for _, c := range n.patterns {
+			if c.seg.key == seg.key {
+				return c
+			}
+		}
+		c := newNode(seg)
+		n.patterns = append(n.patterns, c)
+		return c
+	case segmentParam:
+		if n.param == nil {
+			n.param = newNode(seg)
+		}
+		return n.param
+	default:
+		if n.path == nil {
+			n.path = newNode(seg)
+		}
+		return n.path
+	}
+}
+
+// 插入接口，相同路径、相同请求方式并且版本有交集的接口视为冲突
+func (n *node) insert(api *restfulMethod) error {
+	cur := n
+	for _, seg := range api._segments {
+		cur = cur.child(seg)
+	}
+	for _, exist := range cur.apis {
+		if exist._method != api._method {
+			continue
+		}
+		if versions := exist.overlap(api); len(versions) > 0 || (len(exist._versions) == 0 && len(api._versions) == 0) {
+			return fmt.Errorf("restful api conflict: %s %s (%s) and %s %s (%s), versions %v",
+				api._method, api._uri, api._action, exist._method, exist._uri, exist._action, versions)
+		}
+	}
+	cur.apis = append(cur.apis, api)
+	return nil
+}
+
+// 按优先级查找路径匹配的节点，accept返回true时结束查找
+// values 为已匹配的变量值，按在路径中出现的顺序排列
+func (n *node) match(parts []string, values []string, accept func(n *node, values []string) bool) bool {
+	if len(parts) == 0 {
+		return len(n.apis) > 0 && accept(n, values)
+	}
+	part := parts[0]
+
+	if c, ok := n.static[part]; ok {
+		if c.match(parts[1:], values, accept) {
+			return true
+		}
+	}
+	for _, c := range n.patterns {
+		if sub := c.seg.pattern.FindStringSubmatch(part); sub != nil {
+			if c.match(parts[1:], append(values[:len(values):len(values)], sub[1:]...), accept) {
+				return true
+			}
+		}
+	}
+	if n.param != nil && valueReg.MatchString(part) {
+		if n.param.match(parts[1:], append(values[:len(values):len(values)], part), accept) {
+			return true
+		}
+	}
+	if n.path != nil {
+		// 路径变量优先匹配尽可能少的段，使后面带有静态段的接口优先
+		for i := 1; i <= len(parts); i++ {
+			value := strings.Join(parts[:i], "/")
+			if !pathValueReg.MatchString(value) {
+				continue
+			}
+			if n.path.match(parts[i:], append(values[:len(values):len(values)], value), accept) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 将uri拆分为路径段
+// pathFields 中的变量为路径变量，支持带或不带大括号，例如 "{path}" 或 "path"
+func parseSegments(uri string, pathFields []string) ([]segment, error) {
+	isPathField := func(field string) bool {
+		for _, f := range pathFields {
+			if f == field || "{"+f+"}" == field {
+				return true
+			}
+		}
+		return false
+	}
+
+	segments := make([]segment, 0)
+	for _, part := range splitPath(uri) {
+		locs := fieldReg.FindAllStringIndex(part, -1)
+		switch {
+		case len(locs) == 0:
+			segments = append(segments, segment{kind: segmentStatic, key: part})
+		case len(locs) == 1 && locs[0][0] == 0 && locs[0][1] == len(part):
+			if isPathField(part) {
+				segments = append(segments, segment{kind: segmentPath, key: "{}"})
+			} else {
+				segments = append(segments, segment{kind: segmentParam, key: "{}"})
+			}
+		default:
+			// 段内包含变量，变量之间必须有静态内容分隔
+			reg, key := "^", ""
+			last := 0
+			for i, loc := range locs {
+				if i > 0 && loc[0] == last {
+					return nil, fmt.Errorf("restful uri %s: adjacent fields in segment %s", uri, part)
+				}
+				if isPathField(part[loc[0]:loc[1]]) {
+					return nil, fmt.Errorf("restful uri %s: path field must be a whole segment", uri)
+				}
+				reg += regexp.QuoteMeta(part[last:loc[0]]) + ValueReg
+				key += part[last:loc[0]] + "{}"
+				last = loc[1]
+			}
+			reg += regexp.QuoteMeta(part[last:]) + "$"
+			key += part[last:]
+			segments = append(segments, segment{kind: segmentPattern, key: key, pattern: regexp.MustCompile(reg)})
+		}
+	}
+	return segments, nil
+}
+
+// 按"/"拆分路径，忽略首尾和重复的"/"
+func splitPath(path string) []string {
+	parts := make([]string, 0)
+	for _, p := range strings.Split(path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// 两个接口共同支持的版本
+func (this *restfulMethod) overlap(other *restfulMethod) []string {
+	versions := make([]string, 0)
+	for _, v := range this._versions {
+		if other.contains(v) {
+			versions = append(versions, v)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
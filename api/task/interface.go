@@ -14,6 +14,17 @@ type TaskService interface {
 	UpdateTask(request *UpdateTaskRequest) (*UpdateTaskResponse, e.ApiError)
 }
 
+// 支持取消任务的任务接口，TaskService 的实现可以选择实现
+// 例如：
+//
+//	if canceler, ok := service.(task.TaskCanceler); ok {
+//	    canceler.CancelTask(request)
+//	}
+type TaskCanceler interface {
+	// 取消任务
+	CancelTask(request *CancelTaskRequest) (*CancelTaskResponse, e.ApiError)
+}
+
 type TaskCreator interface {
 	// 创建任务
 	Create(kind TaskKind, name, creator, description, params string) (string, error)
@@ -30,3 +30,10 @@ type UpdateTaskRequest struct {
 	Detail      string `json:"detail"`
 	Description string `json:"description"`
 }
+
+type CancelTaskRequest struct {
+	api.Request
+	RefID string `json:"refId"`
+	// 取消原因
+	Reason string `json:"reason"`
+}
@@ -25,3 +25,10 @@ type DescribeTasksBriefResponse struct {
 type UpdateTaskResponse struct {
 	api.Response
 }
+
+// CancelTaskResponse response for cancel task
+type CancelTaskResponse struct {
+	api.Response
+	// 取消后的任务状态，canceled或canceling
+	Status string `json:"status"`
+}
@@ -0,0 +1,107 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/validator"
+)
+
+// 任务类型对应的参数结构
+// 例子：
+// type VMMigrationParams struct {
+//     InstanceId string `json:"instanceId" maxLen:"64" verf`
+//     TargetHost string `json:"targetHost" ipv4 verf`
+// }
+// task.RegisterParams(task.TaskKindVMMigration, &VMMigrationParams{})
+var taskKindParams = struct {
+	lck   sync.RWMutex
+	types map[TaskKind]reflect.Type
+}{types: make(map[TaskKind]reflect.Type)}
+
+// RegisterParams 为任务类型注册参数结构，参数结构的字段使用validator的标签描述校验规则
+func RegisterParams(kind TaskKind, params interface{}) {
+	paramsType := reflect.TypeOf(params)
+	if paramsType == nil {
+		panic(fmt.Sprintf("task kind %s params is nil", kind))
+	}
+	if paramsType.Kind() == reflect.Ptr {
+		paramsType = paramsType.Elem()
+	}
+	if paramsType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("task kind %s params must be struct, got %s", kind, paramsType))
+	}
+
+	taskKindParams.lck.Lock()
+	defer taskKindParams.lck.Unlock()
+	taskKindParams.types[kind] = paramsType
+}
+
+// ParamsType 获取任务类型注册的参数结构，未注册时返回nil
+func (tk TaskKind) ParamsType() reflect.Type {
+	taskKindParams.lck.RLock()
+	defer taskKindParams.lck.RUnlock()
+	return taskKindParams.types[tk]
+}
+
+// DecodeParams 按任务类型注册的参数结构解析并校验params，返回参数结构的指针
+// 未注册参数结构的任务类型不做校验，返回nil
+func (tk TaskKind) DecodeParams(params string) (interface{}, e.ApiError) {
+	paramsType := tk.ParamsType()
+	if paramsType == nil {
+		return nil, nil
+	}
+
+	// 解析参数
+	value := reflect.New(paramsType).Interface()
+	if strings.TrimSpace(params) != "" {
+		if err := json.Unmarshal([]byte(params), value); err != nil {
+			return nil, paramsDecodeError(tk, err)
+		}
+	}
+
+	// 校验参数
+	if err := validator.Validate(value); err != nil {
+		if apiErr, ok := err.(e.ApiError); ok {
+			return nil, apiErr
+		}
+		return nil, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+	}
+	return value, nil
+}
+
+// 参数解析错误
+func paramsDecodeError(tk TaskKind, err error) e.ApiError {
+	apiErr := e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid params of task kind %s", tk), err)
+	switch v := err.(type) {
+	case *json.UnmarshalTypeError:
+		apiErr.AddDetail("field", v.Field)
+		apiErr.AddDetail("message", fmt.Sprintf("cannot be %s, expect %s", v.Value, v.Type))
+	case *json.SyntaxError:
+		apiErr.AddDetail("message", fmt.Sprintf("%s at offset %d", v.Error(), v.Offset))
+	default:
+		apiErr.AddDetail("message", err.Error())
+	}
+	return apiErr
+}
+
+// 创建任务前校验任务参数
+type validatedCreator struct {
+	creator TaskCreator
+}
+
+// NewValidatedCreator 包装TaskCreator，创建任务前按任务类型注册的参数结构校验params
+func NewValidatedCreator(creator TaskCreator) TaskCreator {
+	return &validatedCreator{creator: creator}
+}
+
+func (c *validatedCreator) Create(kind TaskKind, name, creator, description, params string) (string, error) {
+	if _, err := kind.DecodeParams(params); err != nil {
+		return "", err
+	}
+	return c.creator.Create(kind, name, creator, description, params)
+}
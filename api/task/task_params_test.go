@@ -0,0 +1,63 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/stretchr/testify/assert"
+)
+
+type demoParams struct {
+	Name  string `json:"name" maxLen:"8" verf:""`
+	Mode  string `json:"mode" inList:"fast,slow" nilable:"" verf:""`
+	Count int    `json:"count" biggerEq:"1" lowerEq:"10" verf:""`
+}
+
+type recordCreator struct {
+	called bool
+}
+
+func (c *recordCreator) Create(kind TaskKind, name, creator, description, params string) (string, error) {
+	c.called = true
+	return "task-1", nil
+}
+
+func TestTaskKind_DecodeParams(t *testing.T) {
+	RegisterParams(TaskKindAsyncDemo, &demoParams{})
+
+	v, err := TaskKindAsyncDemo.DecodeParams(`{"name":"demo","mode":"fast","count":3}`)
+	assert.Nil(t, err)
+	assert.Equal(t, &demoParams{Name: "demo", Mode: "fast", Count: 3}, v)
+
+	_, err = TaskKindAsyncDemo.DecodeParams(`{"name":"demo","count":"3"}`)
+	assert.Equal(t, e.INVALID_ARGUMENT.Type, err.GetType())
+	assert.Equal(t, "count", err.GetDetails()[0]["field"])
+
+	_, err = TaskKindAsyncDemo.DecodeParams(`{"name":"demo","mode":"medium","count":3}`)
+	assert.Equal(t, e.INVALID_ARGUMENT.Type, err.GetType())
+	assert.Equal(t, "mode", err.GetDetails()[0]["field"])
+
+	_, err = TaskKindAsyncDemo.DecodeParams(`{"count":3}`)
+	assert.Equal(t, "name", err.GetDetails()[0]["field"])
+
+	// 未注册参数结构的任务类型不校验
+	v, err = TaskKindBilling.DecodeParams(`not json`)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+}
+
+func TestNewValidatedCreator(t *testing.T) {
+	RegisterParams(TaskKindAsyncDemo, &demoParams{})
+
+	rc := &recordCreator{}
+	creator := NewValidatedCreator(rc)
+
+	_, err := creator.Create(TaskKindAsyncDemo, "demo", "admin", "", `{"name":"demo","count":0}`)
+	assert.NotNil(t, err)
+	assert.Equal(t, false, rc.called)
+
+	id, err := creator.Create(TaskKindAsyncDemo, "demo", "admin", "", `{"name":"demo","count":1}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "task-1", id)
+	assert.Equal(t, true, rc.called)
+}
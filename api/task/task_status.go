@@ -1,7 +1,13 @@
 package task
 
+import "time"
+
 type TaskStatus int
 
+// 取消中的任务等待执行者响应的最长时间，与取消信号的保留时间一致
+// 执行者失联时取消信号随租约过期，超过此时间的取消中任务需要由调用方置为已取消
+const CancelingTimeout = 600 * time.Second
+
 const (
 	TaskStatusUnknown    TaskStatus = -1 // 未知状态
 	TaskStatusCreated    TaskStatus = 1  // 任务被成功创建
@@ -9,7 +15,8 @@ const (
 	TaskStatusRunning    TaskStatus = 3  // 任务正在被执行
 	TaskStatusFailed     TaskStatus = 4  // 任务失败
 	TaskStatusSucceed    TaskStatus = 5  // 任务成功
-	TaskStatusCanceled   TaskStatus = 6  // 任务被取消，保留字段，暂时没有使用
+	TaskStatusCanceled   TaskStatus = 6  // 任务被取消
+	TaskStatusCanceling  TaskStatus = 7  // 任务正在取消，等待执行者响应取消信号
 )
 
 // taskStatusText 状态文本描述
@@ -21,16 +28,19 @@ var taskStatusText = map[TaskStatus]string{
 	TaskStatusFailed:     "failed",
 	TaskStatusSucceed:    "succeed",
 	TaskStatusCanceled:   "canceled",
+	TaskStatusCanceling:  "canceling",
 }
 
 // 状态机流转限制
 var statusTransitionsLimit = map[TaskStatus][]TaskStatus{
 	TaskStatusCreated:    {TaskStatusDispatched, TaskStatusCanceled},
-	TaskStatusDispatched: {TaskStatusRunning},
-	TaskStatusRunning:    {TaskStatusFailed, TaskStatusSucceed},
+	TaskStatusDispatched: {TaskStatusRunning, TaskStatusCanceling},
+	TaskStatusRunning:    {TaskStatusFailed, TaskStatusSucceed, TaskStatusCanceling},
 	TaskStatusFailed:     {TaskStatusCanceled},
 	TaskStatusSucceed:    {},
 	TaskStatusCanceled:   {},
+	// 执行者收到取消信号后置为已取消，取消信号送达前任务可能已经执行结束
+	TaskStatusCanceling: {TaskStatusCanceled, TaskStatusFailed, TaskStatusSucceed},
 }
 
 // 需要被分配的任务状态
@@ -71,6 +81,34 @@ func (s TaskStatus) Finished() bool {
 	return s == TaskStatusSucceed || s == TaskStatusCanceled
 }
 
+// 判断是否可以被取消，正在取消中的任务不能重复取消
+func (s TaskStatus) Cancelable() bool {
+	return s.CancelTarget() != TaskStatusUnknown
+}
+
+// 取消任务时应流转到的状态，未分配的任务直接取消，已分配的任务先进入取消中，等待执行者响应
+func (s TaskStatus) CancelTarget() TaskStatus {
+	if s == TaskStatusCanceling {
+		return TaskStatusUnknown
+	}
+	if s.TransitionTo(TaskStatusCanceled) {
+		return TaskStatusCanceled
+	}
+	if s.TransitionTo(TaskStatusCanceling) {
+		return TaskStatusCanceling
+	}
+	return TaskStatusUnknown
+}
+
+// 取消中的任务超过 CancelingTimeout 未被执行者响应时应流转到的状态，updatedAt 为进入取消中的时间
+// 未超时或不是取消中的任务返回 TaskStatusUnknown，调用方应在扫描未完成任务时调用
+func (s TaskStatus) ExpireTarget(updatedAt, now time.Time) TaskStatus {
+	if s != TaskStatusCanceling || now.Sub(updatedAt) < CancelingTimeout {
+		return TaskStatusUnknown
+	}
+	return TaskStatusCanceled
+}
+
 // 状态流转校验
 func (s TaskStatus) TransitionTo(to TaskStatus) bool {
 	if s == 0 || to == 0 {
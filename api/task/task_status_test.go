@@ -3,6 +3,7 @@ package task
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestTaskStatus_TransitionTo(t *testing.T) {
@@ -32,3 +33,21 @@ func TestTaskStatus_Finished(t *testing.T) {
 	passed = status.Finished()
 	assert.Equal(t, false, passed)
 }
+
+func TestTaskStatus_CancelTarget(t *testing.T) {
+	assert.Equal(t, TaskStatusCanceled, TaskStatusCreated.CancelTarget())
+	assert.Equal(t, TaskStatusCanceling, TaskStatusDispatched.CancelTarget())
+	assert.Equal(t, TaskStatusCanceling, TaskStatusRunning.CancelTarget())
+	assert.Equal(t, TaskStatusUnknown, TaskStatusSucceed.CancelTarget())
+	assert.Equal(t, false, TaskStatusCanceling.Cancelable())
+
+	assert.Equal(t, true, TaskStatusCanceling.TransitionTo(TaskStatusCanceled))
+	assert.Equal(t, false, TaskStatusCanceling.Finished())
+}
+
+func TestTaskStatus_ExpireTarget(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, TaskStatusUnknown, TaskStatusCanceling.ExpireTarget(now.Add(-time.Minute), now))
+	assert.Equal(t, TaskStatusCanceled, TaskStatusCanceling.ExpireTarget(now.Add(-CancelingTimeout), now))
+	assert.Equal(t, TaskStatusUnknown, TaskStatusRunning.ExpireTarget(now.Add(-CancelingTimeout), now))
+}
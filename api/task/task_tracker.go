@@ -0,0 +1,128 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/logger"
+)
+
+// 运行中任务的统计，停机排空时停止接收新任务并等待运行中的任务结束
+// 不使用服务注册时，需要自行加入全局资源管理的排空阶段：global.DefaultResourceManager.AddDrainer(tracker)
+// 例子：
+//
+//	tracker := task.NewTaskTracker()
+//	service.SetTaskTracker(tracker) // 注册信息中上报运行中的任务数，服务排空时随之排空
+//	if err := tracker.Begin(refID); err != nil {
+//	    return err
+//	}
+//	defer tracker.End(refID)
+type TaskTracker struct {
+	mu       sync.Mutex
+	running  map[string]time.Time
+	total    int
+	done     int
+	draining bool
+	idle     chan struct{} // 没有运行中的任务时关闭
+}
+
+func NewTaskTracker() *TaskTracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &TaskTracker{
+		running: make(map[string]time.Time),
+		idle:    idle,
+	}
+}
+
+// Begin 开始执行任务，排空阶段返回 UNAVAILABLE 错误，重复开始同一个任务返回 ALREADY_EXISTS 错误
+func (t *TaskTracker) Begin(refID string) e.ApiError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return e.NewApiError(e.UNAVAILABLE, "service is draining, task "+refID+" rejected", nil)
+	}
+	if _, ok := t.running[refID]; ok {
+		return e.NewApiError(e.ALREADY_EXISTS, "task "+refID+" is running", nil)
+	}
+	if len(t.running) == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.running[refID] = time.Now()
+	t.total++
+	return nil
+}
+
+// End 任务执行结束
+func (t *TaskTracker) End(refID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.running[refID]; !ok {
+		return
+	}
+	delete(t.running, refID)
+	t.done++
+	if len(t.running) == 0 {
+		close(t.idle)
+	}
+}
+
+// Running 运行中的任务
+func (t *TaskTracker) Running() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	refIDs := make([]string, 0, len(t.running))
+	for refID := range t.running {
+		refIDs = append(refIDs, refID)
+	}
+	return refIDs
+}
+
+// Count 开始过的任务总数、运行中的任务数、已结束的任务数
+func (t *TaskTracker) Count() (total, running, done int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total, len(t.running), t.done
+}
+
+// Pending 运行中的任务数，排空阶段的状态接口据此显示进度
+func (t *TaskTracker) Pending() int {
+	_, running, _ := t.Count()
+	return running
+}
+
+// Draining 是否处于排空阶段
+func (t *TaskTracker) Draining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+// Drain 停止接收新任务，等待运行中的任务结束，ctx到期时返回 DEADLINE_EXCEEDED 错误
+func (t *TaskTracker) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	idle := t.idle
+	running := len(t.running)
+	t.mu.Unlock()
+
+	logger.Info("[task] [tracker] stop accepting tasks, waiting for %d running tasks", running)
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-idle:
+			logger.Info("[task] [tracker] all tasks finished")
+			return nil
+		case <-ctx.Done():
+			refIDs := t.Running()
+			logger.Warn("[task] [tracker] drain timeout, %d tasks still running: %v", len(refIDs), refIDs)
+			return e.NewApiError(e.DEADLINE_EXCEEDED, "drain timeout with running tasks", ctx.Err())
+		case <-ticker.C:
+			_, running, _ := t.Count()
+			logger.Info("[task] [tracker] waiting for %d running tasks", running)
+		}
+	}
+}
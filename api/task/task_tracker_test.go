@@ -0,0 +1,58 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskTracker_Drain(t *testing.T) {
+	tracker := NewTaskTracker()
+	assert.Nil(t, tracker.Begin("task-1"))
+	assert.Equal(t, e.ALREADY_EXISTS.Type, tracker.Begin("task-1").GetType())
+
+	drained := make(chan error, 1)
+	go func() { drained <- tracker.Drain(context.Background()) }()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, tracker.Draining())
+	assert.Equal(t, e.UNAVAILABLE.Type, tracker.Begin("task-2").GetType())
+
+	select {
+	case <-drained:
+		t.Fatal("drained with running tasks")
+	default:
+	}
+
+	tracker.End("task-1")
+	select {
+	case err := <-drained:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("drain not finished")
+	}
+
+	total, running, done := tracker.Count()
+	assert.Equal(t, []int{1, 0, 1}, []int{total, running, done})
+	assert.Equal(t, 0, tracker.Pending())
+}
+
+func TestTaskTracker_DrainTimeout(t *testing.T) {
+	tracker := NewTaskTracker()
+	assert.Nil(t, tracker.Begin("task-1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := tracker.Drain(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, e.DEADLINE_EXCEEDED.Code, err.(e.ApiError).GetCode())
+	assert.Equal(t, []string{"task-1"}, tracker.Running())
+}
+
+func TestTaskTracker_DrainIdle(t *testing.T) {
+	tracker := NewTaskTracker()
+	assert.Nil(t, tracker.Drain(context.Background()))
+}
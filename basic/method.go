@@ -5,6 +5,7 @@ package basic
 //**************************************
 
 import (
+	"context"
 	"github.com/Zoxu0928/task-common/logger"
 	"github.com/Zoxu0928/task-common/tools"
 	"reflect"
@@ -19,10 +20,13 @@ type Method struct {
 	path    string         //方法路径，示例：
 	class   *Class         //所属类，当方法不是某类中的方法时，则所属类是空的，只有类实现的方法，此参数才有值并有意义
 	funC    interface{}    //目标方法
-	args    []reflect.Type //入参类型
+	withCtx bool           //第一个入参是否为 context.Context
+	args    []reflect.Type //入参类型，不包含 context.Context
 	returN  []reflect.Type //出参类型
 }
 
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
 // 实例化
 func NewMethod(cls *Class, v interface{}) *Method {
 	return createMethod(cls, v)
@@ -55,6 +59,11 @@ func (this *Method) GetReturn() []reflect.Type {
 	return this.returN
 }
 
+// 方法的第一个入参是否为 context.Context，为true时 GetArgs 中不包含这个参数
+func (this *Method) HasContext() bool {
+	return this.withCtx
+}
+
 func (this *Method) GetClass() *Class {
 	return this.class
 }
@@ -65,9 +74,20 @@ func (this *Method) GetFullName() string {
 
 // 反射调用方法
 func (this *Method) Invoke(args []reflect.Value) []reflect.Value {
-	if this.GetArgs()[0].Kind() == reflect.Struct && len(args) > 0 {
+	return this.InvokeContext(context.Background(), args)
+}
+
+// 反射调用方法，方法声明了 context.Context 入参时传入ctx
+func (this *Method) InvokeContext(ctx context.Context, args []reflect.Value) []reflect.Value {
+	if len(this.GetArgs()) > 0 && this.GetArgs()[0].Kind() == reflect.Struct && len(args) > 0 {
 		args[0] = args[0].Elem()
 	}
+	if this.withCtx {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+	}
 	return reflect.ValueOf(this.funC).Call(args)
 }
 
@@ -78,6 +98,7 @@ func createMethod(cls *Class, f interface{}) *Method {
 
 	var funcTarget interface{}
 	var name, path string
+	var withCtx bool
 	args := make([]reflect.Type, 0)
 	outs := make([]reflect.Type, 0)
 
@@ -98,6 +119,10 @@ func createMethod(cls *Class, f interface{}) *Method {
 
 		// 处理入参
 		rt := rv.Type
+		if rt.NumIn() > i && rt.In(i) == contextType {
+			withCtx = true
+			i++
+		}
 		for ; i < rt.NumIn(); i++ {
 			args = append(args, rt.In(i))
 		}
@@ -120,7 +145,12 @@ func createMethod(cls *Class, f interface{}) *Method {
 		name, path = getNameAndPath(path)
 
 		fType := reflect.TypeOf(f)
-		for i := 0; i < fType.NumIn(); i++ {
+		i := 0
+		if fType.NumIn() > 0 && fType.In(0) == contextType {
+			withCtx = true
+			i++
+		}
+		for ; i < fType.NumIn(); i++ {
 			args = append(args, fType.In(i))
 		}
 		for i := 0; i < fType.NumOut(); i++ {
@@ -152,6 +182,7 @@ func createMethod(cls *Class, f interface{}) *Method {
 		path:    path,
 		class:   cls,
 		funC:    funcTarget,
+		withCtx: withCtx,
 		args:    args,
 		returN:  outs,
 	}
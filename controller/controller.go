@@ -45,6 +45,17 @@ func (this *controller) GetController(methodName, version string) *basic.Method
 	return this.methods[this.getControllerKey(version, methodName)]
 }
 
+// 获得所有方法，key为 版本.方法名称，与 GetController 的查找方式一致
+func (this *controller) GetAllController() map[string]*basic.Method {
+	this.mmu.RLock()
+	defer this.mmu.RUnlock()
+	methods := make(map[string]*basic.Method, len(this.methods))
+	for k, v := range this.methods {
+		methods[k] = v
+	}
+	return methods
+}
+
 // 向全局路由中增加controller
 func AddController(v interface{}) {
 	default_controller.AddController(v)
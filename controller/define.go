@@ -13,6 +13,7 @@ import (
 type Controller interface {
 	AddController(v interface{})
 	GetController(methodName, version string) *basic.Method
+	GetAllController() map[string]*basic.Method
 	UseModel() bool
 }
 
@@ -0,0 +1,140 @@
+package distribute_mutex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// etcd上的一个排队节点，节点按创建版本号排队，节点随session的租约过期自动删除
+// 读写锁和信号量都基于它实现
+type etcdWaiter struct {
+	session *concurrency.Session
+	prefix  string // 排队的路径前缀，所有参与者在同一前缀下排队
+	myKey   string
+	myRev   int64
+}
+
+func newEtcdWaiter(session *concurrency.Session, prefix, role string) *etcdWaiter {
+	return &etcdWaiter{
+		session: session,
+		prefix:  strings.TrimRight(prefix, "/") + "/",
+		myKey:   fmt.Sprintf("%s/%s/%x", strings.TrimRight(prefix, "/"), role, session.Lease()),
+		myRev:   -1,
+	}
+}
+
+// 创建排队节点，已存在时复用
+func (w *etcdWaiter) enqueue(ctx context.Context) error {
+	client := w.session.Client()
+	cmp := clientv3.Compare(clientv3.CreateRevision(w.myKey), "=", 0)
+	put := clientv3.OpPut(w.myKey, "", clientv3.WithLease(w.session.Lease()))
+	get := clientv3.OpGet(w.myKey)
+	resp, err := client.Txn(ctx).If(cmp).Then(put).Else(get).Commit()
+	if err != nil {
+		return err
+	}
+	w.myRev = resp.Header.Revision
+	if !resp.Succeeded {
+		w.myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	return nil
+}
+
+// 获取排在自己前面的节点，按创建版本号升序
+func (w *etcdWaiter) ahead(ctx context.Context) ([]*mvccpb.KeyValue, int64, error) {
+	client := w.session.Client()
+	resp, err := client.Get(ctx, w.prefix, clientv3.WithPrefix(),
+		clientv3.WithMaxCreateRev(w.myRev-1),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend))
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Kvs, resp.Header.Revision, nil
+}
+
+// 排队等待，blocked返回阻塞自己的节点，为空时获得锁
+// try为true时不等待，被阻塞时删除排队节点并返回 concurrency.ErrLocked
+func (w *etcdWaiter) acquire(ctx context.Context, try bool, blocked func(kvs []*mvccpb.KeyValue) []*mvccpb.KeyValue) error {
+	if err := w.enqueue(ctx); err != nil {
+		return err
+	}
+	for {
+		kvs, rev, err := w.ahead(ctx)
+		if err != nil {
+			w.release(context.Background())
+			return err
+		}
+		blockers := blocked(kvs)
+		if len(blockers) == 0 {
+			return nil
+		}
+		if try {
+			if err := w.release(ctx); err != nil {
+				return err
+			}
+			return concurrency.ErrLocked
+		}
+		// 等待任意一个阻塞节点被删除后重新检查
+		if err := w.waitDelete(ctx, blockers, rev+1); err != nil {
+			w.release(context.Background())
+			return err
+		}
+	}
+}
+
+// 等待任意一个节点被删除
+func (w *etcdWaiter) waitDelete(ctx context.Context, kvs []*mvccpb.KeyValue, rev int64) error {
+	keys := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		keys[string(kv.Key)] = struct{}{}
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := w.session.Client().Watch(cctx, w.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev), clientv3.WithFilterPut())
+	for wresp := range wch {
+		if err := wresp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wresp.Events {
+			if _, ok := keys[string(ev.Kv.Key)]; ok {
+				return nil
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-w.session.Done():
+		return concurrency.ErrSessionExpired
+	default:
+	}
+	return fmt.Errorf("lost watcher waiting for delete")
+}
+
+// 删除排队节点
+func (w *etcdWaiter) release(ctx context.Context) error {
+	if w.myRev < 0 {
+		return nil
+	}
+	if _, err := w.session.Client().Delete(ctx, w.myKey); err != nil {
+		return err
+	}
+	w.myRev = -1
+	return nil
+}
+
+// 节点的角色
+func roleOf(prefix string, kv *mvccpb.KeyValue) string {
+	role := strings.TrimPrefix(string(kv.Key), prefix)
+	if i := strings.Index(role, "/"); i >= 0 {
+		role = role[:i]
+	}
+	return role
+}
@@ -95,6 +95,76 @@ func (mutex *EtcdMutex) UnLock(ctx context.Context) error {
 	return mutex.etcdMutex.Unlock(baseCtx)
 }
 
+// Acquire 阻塞获取锁，返回持有锁的句柄
+// session已过期时先创建新的session
+func (mutex *EtcdMutex) Acquire(ctx context.Context) (*LockHandle, error) {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	if err := mutex.renew(); err != nil {
+		return nil, err
+	}
+	session, etcdMutex := mutex.session, mutex.etcdMutex
+	if err := etcdMutex.Lock(baseCtx); err != nil {
+		return nil, err
+	}
+	handle, err := newLockHandle(baseCtx, session, etcdMutex)
+	if err != nil {
+		etcdMutex.Unlock(context.Background())
+		return nil, err
+	}
+	return handle, nil
+}
+
+// TryAcquire 尝试获取锁，被其他session持有时返回 concurrency.ErrLocked
+func (mutex *EtcdMutex) TryAcquire(ctx context.Context) (*LockHandle, error) {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	if err := mutex.TryLock(baseCtx); err != nil {
+		return nil, err
+	}
+	handle, err := newLockHandle(baseCtx, mutex.session, mutex.etcdMutex)
+	if err != nil {
+		mutex.etcdMutex.Unlock(context.Background())
+		return nil, err
+	}
+	return handle, nil
+}
+
+// WithLock 阻塞获取锁后执行fn，fn返回后释放锁
+// 锁丢失时取消fn的ctx，并返回 DistributeMutexLostError，fn中可以通过 FencingToken 获取栅栏令牌
+func (mutex *EtcdMutex) WithLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	handle, err := mutex.Acquire(baseCtx)
+	if err != nil {
+		return err
+	}
+	return withLock(baseCtx, handle, fn)
+}
+
+// session过期时创建新的session
+func (mutex *EtcdMutex) renew() error {
+	select {
+	case <-mutex.session.Done():
+	default:
+		return nil
+	}
+	logger.Info("etcd lease [%x] is expired ! create a new lease......", mutex.session.Lease())
+	newMutex, err := NewETCDDistributeMutex(mutex.client, mutex.prefix, time.Duration(mutex.ttl)*time.Second)
+	if err != nil {
+		return err
+	}
+	mutex.session = newMutex.session
+	mutex.etcdMutex = newMutex.etcdMutex
+	return nil
+}
+
 func (mutex *EtcdMutex) Kind() string {
 	return DistributeMutexKind
 }
@@ -0,0 +1,214 @@
+package distribute_mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd"
+	"github.com/Zoxu0928/task-common/etcd/test-server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcdMutex(t *testing.T) {
+	server := test_server.New(t)
+	testMutexConformance(t, func(t *testing.T, name string) IMutex {
+		m, err := NewETCDDistributeMutex(server.Client, "/lock/"+name, 5*time.Second)
+		assert.Nil(t, err)
+		return m
+	})
+}
+
+func TestEtcdMutex_TryLockLeaseRecovery(t *testing.T) {
+	server := test_server.New(t)
+	m, err := NewETCDDistributeMutex(server.Client, "/lock/recovery", 5*time.Second)
+	assert.Nil(t, err)
+
+	// 租约被撤销后，TryLock创建新的租约并获取锁
+	oldLease := m.session.Lease()
+	_, err = server.Client.Revoke(context.Background(), oldLease)
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.TryLock(context.Background()))
+	assert.NotEqual(t, oldLease, m.session.Lease())
+
+	other, err := NewETCDDistributeMutex(server.Client, "/lock/recovery", 5*time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrLocked, other.TryLock(context.Background()))
+	assert.Nil(t, m.UnLock(context.Background()))
+	assert.Nil(t, other.TryLock(context.Background()))
+}
+
+func TestEtcdMutex_LockHandle(t *testing.T) {
+	server := test_server.New(t)
+	a, err := NewETCDDistributeMutex(server.Client, "/lock/handle", 5*time.Second)
+	assert.Nil(t, err)
+	b, err := NewETCDDistributeMutex(server.Client, "/lock/handle", 5*time.Second)
+	assert.Nil(t, err)
+
+	h1, err := a.Acquire(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, h1.UnLock(context.Background()))
+	assert.False(t, h1.IsLost())
+
+	// 后获取锁的持有者的token更大
+	h2, err := b.Acquire(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, h2.Token() > h1.Token())
+
+	// 租约被撤销后锁丢失
+	_, err = server.Client.Revoke(context.Background(), b.session.Lease())
+	assert.Nil(t, err)
+	select {
+	case <-h2.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock lost not notified")
+	}
+}
+
+func TestEtcdMutex_LockHandleRewatch(t *testing.T) {
+	server := test_server.New(t)
+	m, err := NewETCDDistributeMutex(server.Client, "/lock/rewatch", 5*time.Second)
+	assert.Nil(t, err)
+	h, err := m.Acquire(context.Background())
+	assert.Nil(t, err)
+
+	// 重新监听从检查时的版本之后开始，之后的删除可以收到
+	wch, exists := h.rewatch(context.Background())
+	assert.True(t, exists)
+	_, err = server.Client.Delete(context.Background(), h.Key())
+	assert.Nil(t, err)
+	select {
+	case wresp := <-wch:
+		assert.Equal(t, 1, len(wresp.Events))
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock key delete not watched")
+	}
+
+	// 锁节点已不存在
+	_, exists = h.rewatch(context.Background())
+	assert.False(t, exists)
+
+	// 主动释放后立即返回，不再等待
+	assert.Nil(t, h.UnLock(context.Background()))
+	start := time.Now()
+	wch, _ = h.rewatch(context.Background())
+	assert.Nil(t, wch)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestEtcdMutex_WithLock(t *testing.T) {
+	server := test_server.New(t)
+	m, err := NewETCDDistributeMutex(server.Client, "/lock/with-lock", 5*time.Second)
+	assert.Nil(t, err)
+
+	err = m.WithLock(context.Background(), func(ctx context.Context) error {
+		token, ok := FencingToken(ctx)
+		assert.True(t, ok)
+		assert.True(t, token > 0)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// 执行期间锁丢失时取消ctx
+	err = m.WithLock(context.Background(), func(ctx context.Context) error {
+		_, err := server.Client.Revoke(context.Background(), m.session.Lease())
+		assert.Nil(t, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("ctx not canceled")
+		}
+		return ctx.Err()
+	})
+	assert.Equal(t, DistributeMutexLostError, err)
+}
+
+func TestEtcdRWMutex(t *testing.T) {
+	server := test_server.New(t)
+	newRW := func() *EtcdRWMutex {
+		m, err := NewETCDDistributeRWMutex(server.Client, "/lock/rw", 5*time.Second)
+		assert.Nil(t, err)
+		return m
+	}
+	r1, r2, w := newRW(), newRW(), newRW()
+	ctx := context.Background()
+
+	// 读锁共享，写锁被读锁阻塞
+	assert.Nil(t, r1.RLock(ctx))
+	assert.Nil(t, r2.TryRLock(ctx))
+	assert.Equal(t, ErrLocked, w.TryLock(ctx))
+
+	assert.Nil(t, r1.RUnLock(ctx))
+	assert.Nil(t, r2.RUnLock(ctx))
+	assert.Nil(t, w.TryLock(ctx))
+
+	// 读锁被写锁阻塞
+	assert.Equal(t, ErrLocked, r1.TryRLock(ctx))
+	acquired := make(chan error, 1)
+	go func() { acquired <- r1.RLock(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("read lock acquired while write locked")
+	case <-time.After(300 * time.Millisecond):
+	}
+	assert.Nil(t, w.UnLock(ctx))
+	select {
+	case err := <-acquired:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("read lock not acquired after unlock")
+	}
+	assert.Nil(t, r1.RUnLock(ctx))
+}
+
+func TestEtcdSemaphore(t *testing.T) {
+	server := test_server.New(t)
+	newSem := func() *EtcdSemaphore {
+		m, err := NewETCDDistributeSemaphore(server.Client, "/lock/sem", 2, 5*time.Second)
+		assert.Nil(t, err)
+		return m
+	}
+	s1, s2, s3 := newSem(), newSem(), newSem()
+	ctx := context.Background()
+
+	assert.Nil(t, s1.TryLock(ctx))
+	assert.Nil(t, s2.TryLock(ctx))
+	assert.Equal(t, ErrLocked, s3.TryLock(ctx))
+	assert.Nil(t, s1.UnLock(ctx))
+	assert.Nil(t, s3.TryLock(ctx))
+	assert.Nil(t, s2.UnLock(ctx))
+	assert.Nil(t, s3.UnLock(ctx))
+
+	_, err := NewETCDDistributeSemaphore(server.Client, "/lock/sem", 0, 0)
+	assert.Equal(t, DistributeSemaphoreInvalidSize, err)
+}
+
+func TestNewETCDDistributeLocker(t *testing.T) {
+	_, err := NewETCDDistributeRWMutex(nil, "/lock/rw", 0)
+	assert.Equal(t, DistributeMutexNilETCDClientError, err)
+	_, err = NewETCDDistributeRWMutex(&etcd.Client{}, "", 0)
+	assert.Equal(t, DistributeMutexNilETCDPrefix, err)
+	_, err = NewETCDDistributeSemaphore(&etcd.Client{}, "/lock/sem", 0, 0)
+	assert.Equal(t, DistributeSemaphoreInvalidSize, err)
+}
+
+func TestLockHandle_Lost(t *testing.T) {
+	newHandle := func() *LockHandle {
+		return &LockHandle{key: "/lock/handle", token: 1, lost: make(chan struct{}), released: make(chan struct{})}
+	}
+
+	h := newHandle()
+	assert.False(t, h.IsLost())
+	h.markLost("lock key deleted")
+	assert.True(t, h.IsLost())
+
+	// 主动释放之后不再通知锁丢失
+	h = newHandle()
+	close(h.released)
+	h.markLost("lock key deleted")
+	assert.False(t, h.IsLost())
+
+	_, ok := FencingToken(context.Background())
+	assert.False(t, ok)
+}
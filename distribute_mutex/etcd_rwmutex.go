@@ -0,0 +1,162 @@
+package distribute_mutex
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd"
+	"github.com/Zoxu0928/task-common/logger"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	DistributeRWMutexKind = "etcd-v3-rw"
+
+	roleRead  = "read"
+	roleWrite = "write"
+)
+
+var (
+	_ = IRWMutex(&EtcdRWMutex{})
+)
+
+// Implementation of distributed read-write lock with Etcd
+// 读锁之间共享，写锁独占，按申请顺序排队，先申请的写锁会阻塞后申请的读锁
+type EtcdRWMutex struct {
+	mu      sync.Mutex
+	prefix  string
+	client  *etcd.Client
+	session *concurrency.Session
+	reader  *etcdWaiter
+	writer  *etcdWaiter
+	ttl     int
+}
+
+// Lock 获取写锁，阻塞直到排在前面的读锁和写锁全部释放
+func (mutex *EtcdRWMutex) Lock(ctx context.Context) error {
+	return mutex.acquire(ctx, roleWrite, false)
+}
+
+// TryLock 尝试获取写锁，被占用时返回 concurrency.ErrLocked
+func (mutex *EtcdRWMutex) TryLock(ctx context.Context) error {
+	return mutex.acquire(ctx, roleWrite, true)
+}
+
+func (mutex *EtcdRWMutex) UnLock(ctx context.Context) error {
+	return mutex.release(ctx, roleWrite)
+}
+
+// RLock 获取读锁，阻塞直到排在前面的写锁全部释放
+func (mutex *EtcdRWMutex) RLock(ctx context.Context) error {
+	return mutex.acquire(ctx, roleRead, false)
+}
+
+// TryRLock 尝试获取读锁，被写锁占用时返回 concurrency.ErrLocked
+func (mutex *EtcdRWMutex) TryRLock(ctx context.Context) error {
+	return mutex.acquire(ctx, roleRead, true)
+}
+
+func (mutex *EtcdRWMutex) RUnLock(ctx context.Context) error {
+	return mutex.release(ctx, roleRead)
+}
+
+func (mutex *EtcdRWMutex) Kind() string {
+	return DistributeRWMutexKind
+}
+
+func (mutex *EtcdRWMutex) acquire(ctx context.Context, role string, try bool) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	waiter, err := mutex.waiter(role)
+	if err != nil {
+		return err
+	}
+	return waiter.acquire(baseCtx, try, func(kvs []*mvccpb.KeyValue) []*mvccpb.KeyValue {
+		// 写锁被所有排在前面的节点阻塞，读锁只被写锁阻塞
+		if role == roleWrite {
+			return kvs
+		}
+		blockers := make([]*mvccpb.KeyValue, 0)
+		for _, kv := range kvs {
+			if roleOf(waiter.prefix, kv) == roleWrite {
+				blockers = append(blockers, kv)
+			}
+		}
+		return blockers
+	})
+}
+
+func (mutex *EtcdRWMutex) release(ctx context.Context, role string) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	mutex.mu.Lock()
+	waiter := mutex.reader
+	if role == roleWrite {
+		waiter = mutex.writer
+	}
+	mutex.mu.Unlock()
+	return waiter.release(baseCtx)
+}
+
+// 获取排队节点，session过期时创建新的session
+func (mutex *EtcdRWMutex) waiter(role string) (*etcdWaiter, error) {
+	mutex.mu.Lock()
+	defer mutex.mu.Unlock()
+
+	select {
+	case <-mutex.session.Done():
+		logger.Info("etcd lease [%x] is expired ! create a new lease......", mutex.session.Lease())
+		session, err := concurrency.NewSession(mutex.client.Client, concurrency.WithTTL(mutex.ttl))
+		if err != nil {
+			return nil, err
+		}
+		mutex.session = session
+		mutex.reader = newEtcdWaiter(session, mutex.prefix, roleRead)
+		mutex.writer = newEtcdWaiter(session, mutex.prefix, roleWrite)
+	default:
+	}
+
+	if role == roleWrite {
+		return mutex.writer, nil
+	}
+	return mutex.reader, nil
+}
+
+func NewETCDDistributeRWMutex(etcdClient *etcd.Client, prefix string, ttl time.Duration) (*EtcdRWMutex, error) {
+	if etcdClient == nil {
+		logger.Error(DistributeMutexNilETCDClientError.Error())
+		return nil, DistributeMutexNilETCDClientError
+	}
+
+	if prefix == "" {
+		logger.Error(DistributeMutexNilETCDPrefix.Error())
+		return nil, DistributeMutexNilETCDPrefix
+	}
+
+	ttlV := int(ttl.Seconds())
+	if ttl <= 0 {
+		ttlV = DefaultTTL
+	}
+
+	// 通过租约创建session
+	session, err := concurrency.NewSession(etcdClient.Client, concurrency.WithTTL(ttlV))
+	if err != nil {
+		return nil, err
+	}
+
+	return &EtcdRWMutex{
+		prefix:  prefix,
+		client:  etcdClient,
+		session: session,
+		reader:  newEtcdWaiter(session, prefix, roleRead),
+		writer:  newEtcdWaiter(session, prefix, roleWrite),
+		ttl:     ttlV,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package distribute_mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd"
+	"github.com/Zoxu0928/task-common/logger"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	DistributeSemaphoreKind = "etcd-v3-semaphore"
+
+	roleHolder = "holder"
+)
+
+var (
+	DistributeSemaphoreInvalidSize error = errors.New("[distributeMutex] semaphore size must be positive")
+)
+
+var (
+	_ = IMutex(&EtcdSemaphore{})
+)
+
+// Implementation of distributed counting semaphore with Etcd
+// 同一个prefix下最多size个持有者，按申请顺序排队
+// 注意：同一prefix的所有参与者必须使用相同的size
+type EtcdSemaphore struct {
+	mu      sync.Mutex
+	prefix  string
+	size    int
+	client  *etcd.Client
+	session *concurrency.Session
+	holder  *etcdWaiter
+	ttl     int
+}
+
+// Lock 获取一个许可，阻塞直到排在前面的持有者少于size个
+func (sem *EtcdSemaphore) Lock(ctx context.Context) error {
+	return sem.acquire(ctx, false)
+}
+
+// TryLock 尝试获取一个许可，许可已满时返回 concurrency.ErrLocked
+func (sem *EtcdSemaphore) TryLock(ctx context.Context) error {
+	return sem.acquire(ctx, true)
+}
+
+// UnLock 释放许可
+func (sem *EtcdSemaphore) UnLock(ctx context.Context) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	sem.mu.Lock()
+	holder := sem.holder
+	sem.mu.Unlock()
+	return holder.release(baseCtx)
+}
+
+func (sem *EtcdSemaphore) Kind() string {
+	return DistributeSemaphoreKind
+}
+
+// Size 许可总数
+func (sem *EtcdSemaphore) Size() int {
+	return sem.size
+}
+
+func (sem *EtcdSemaphore) acquire(ctx context.Context, try bool) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	holder, err := sem.waiter()
+	if err != nil {
+		return err
+	}
+	return holder.acquire(baseCtx, try, func(kvs []*mvccpb.KeyValue) []*mvccpb.KeyValue {
+		if len(kvs) < sem.size {
+			return nil
+		}
+		return kvs
+	})
+}
+
+// 获取排队节点，session过期时创建新的session
+func (sem *EtcdSemaphore) waiter() (*etcdWaiter, error) {
+	sem.mu.Lock()
+	defer sem.mu.Unlock()
+
+	select {
+	case <-sem.session.Done():
+		logger.Info("etcd lease [%x] is expired ! create a new lease......", sem.session.Lease())
+		session, err := concurrency.NewSession(sem.client.Client, concurrency.WithTTL(sem.ttl))
+		if err != nil {
+			return nil, err
+		}
+		sem.session = session
+		sem.holder = newEtcdWaiter(session, sem.prefix, roleHolder)
+	default:
+	}
+	return sem.holder, nil
+}
+
+func NewETCDDistributeSemaphore(etcdClient *etcd.Client, prefix string, size int, ttl time.Duration) (*EtcdSemaphore, error) {
+	if etcdClient == nil {
+		logger.Error(DistributeMutexNilETCDClientError.Error())
+		return nil, DistributeMutexNilETCDClientError
+	}
+
+	if prefix == "" {
+		logger.Error(DistributeMutexNilETCDPrefix.Error())
+		return nil, DistributeMutexNilETCDPrefix
+	}
+
+	if size <= 0 {
+		logger.Error(DistributeSemaphoreInvalidSize.Error())
+		return nil, DistributeSemaphoreInvalidSize
+	}
+
+	ttlV := int(ttl.Seconds())
+	if ttl <= 0 {
+		ttlV = DefaultTTL
+	}
+
+	// 通过租约创建session
+	session, err := concurrency.NewSession(etcdClient.Client, concurrency.WithTTL(ttlV))
+	if err != nil {
+		return nil, err
+	}
+
+	return &EtcdSemaphore{
+		prefix:  prefix,
+		size:    size,
+		client:  etcdClient,
+		session: session,
+		holder:  newEtcdWaiter(session, prefix, roleHolder),
+		ttl:     ttlV,
+	}, nil
+}
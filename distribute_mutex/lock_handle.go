@@ -0,0 +1,201 @@
+package distribute_mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/logger"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var (
+	DistributeMutexLostError error = errors.New("[distributeMutex] lock lost")
+)
+
+// 持有锁期间的句柄
+// 锁的租约由session自动续约，session过期或锁节点被删除时 Lost 返回的通道关闭
+// Token 为获取锁时锁节点的创建版本号，后获取锁的持有者的token一定更大，
+// 写入外部存储时携带token，存储端拒绝比已见过的token更小的写入，可以避免锁丢失后的重复执行
+type LockHandle struct {
+	key     string
+	token   int64
+	session *concurrency.Session
+
+	lost     chan struct{}
+	released chan struct{}
+	once     sync.Once
+	cancel   context.CancelFunc
+}
+
+// Token 栅栏令牌，单调递增
+func (h *LockHandle) Token() int64 {
+	return h.token
+}
+
+// Key 锁节点在etcd中的路径
+func (h *LockHandle) Key() string {
+	return h.key
+}
+
+// Lost session过期或锁节点被删除时关闭，主动释放锁时不会关闭
+func (h *LockHandle) Lost() <-chan struct{} {
+	return h.lost
+}
+
+// IsLost 锁是否已丢失
+func (h *LockHandle) IsLost() bool {
+	select {
+	case <-h.lost:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnLock 释放锁并停止监听，锁节点已被其他持有者替换时不做删除
+func (h *LockHandle) UnLock(ctx context.Context) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	h.once.Do(func() {
+		close(h.released)
+		h.cancel()
+	})
+	cmp := clientv3.Compare(clientv3.CreateRevision(h.key), "=", h.token)
+	_, err := h.session.Client().Txn(baseCtx).If(cmp).Then(clientv3.OpDelete(h.key)).Commit()
+	return err
+}
+
+// 监听session过期和锁节点删除
+func (h *LockHandle) watch(ctx context.Context) {
+	wch := h.session.Client().Watch(ctx, h.key, clientv3.WithRev(h.token), clientv3.WithFilterPut())
+	for {
+		select {
+		case <-h.released:
+			return
+		case <-h.session.Done():
+			h.markLost("session expired")
+			return
+		case wresp, ok := <-wch:
+			if !ok {
+				// 监听被关闭，等待session过期或主动释放
+				wch = nil
+				continue
+			}
+			if wresp.CompactRevision != 0 || wresp.Err() != nil {
+				// 版本被压缩或监听出错，检查锁节点是否还在
+				var exists bool
+				if wch, exists = h.rewatch(ctx); !exists {
+					h.markLost("lock key lost")
+					return
+				}
+				continue
+			}
+			for _, ev := range wresp.Events {
+				if ev.Type == clientv3.EventTypeDelete {
+					h.markLost("lock key deleted")
+					return
+				}
+			}
+		}
+	}
+}
+
+// 等待一秒后检查锁节点是否还在，并从检查时的版本之后重新监听，检查之后的删除不会遗漏
+// 锁节点已不存在时返回false；等待期间主动释放、ctx取消或session过期时返回nil通道，由 watch 处理
+func (h *LockHandle) rewatch(ctx context.Context) (clientv3.WatchChan, bool) {
+	for {
+		select {
+		case <-h.released:
+			return nil, true
+		case <-ctx.Done():
+			return nil, true
+		case <-h.session.Done():
+			return nil, true
+		case <-time.After(time.Second):
+		}
+		resp, err := h.session.Client().Get(ctx, h.key)
+		if err != nil {
+			// etcd暂时不可用时以session是否过期为准，稍后再检查
+			continue
+		}
+		if len(resp.Kvs) == 0 || resp.Kvs[0].CreateRevision != h.token {
+			return nil, false
+		}
+		return h.session.Client().Watch(ctx, h.key, clientv3.WithRev(resp.Header.Revision+1), clientv3.WithFilterPut()), true
+	}
+}
+
+func (h *LockHandle) markLost(reason string) {
+	select {
+	case <-h.released:
+		return
+	default:
+	}
+	logger.Warn("[distributeMutex] lock %s lost, token = %d, %s", h.key, h.token, reason)
+	close(h.lost)
+}
+
+// 获取锁成功后创建句柄，锁节点不存在时返回 concurrency.ErrSessionExpired
+func newLockHandle(ctx context.Context, session *concurrency.Session, mutex *concurrency.Mutex) (*LockHandle, error) {
+	resp, err := session.Client().Get(ctx, mutex.Key())
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, concurrency.ErrSessionExpired
+	}
+
+	h := &LockHandle{
+		key:      mutex.Key(),
+		token:    resp.Kvs[0].CreateRevision,
+		session:  session,
+		lost:     make(chan struct{}),
+		released: make(chan struct{}),
+	}
+	var watchCtx context.Context
+	watchCtx, h.cancel = context.WithCancel(context.Background())
+	go h.watch(watchCtx)
+	return h, nil
+}
+
+type fencingTokenKey struct{}
+
+// FencingToken 获取 WithLock 传入的ctx中的栅栏令牌
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
+
+// 持有锁执行fn，锁丢失时取消fn的ctx
+// fn返回后释放锁，执行期间锁丢失时返回 DistributeMutexLostError
+func withLock(ctx context.Context, handle *LockHandle, fn func(ctx context.Context) error) error {
+	fnCtx, cancel := context.WithCancel(context.WithValue(ctx, fencingTokenKey{}, handle.Token()))
+	defer cancel()
+	go func() {
+		select {
+		case <-handle.Lost():
+			cancel()
+		case <-fnCtx.Done():
+		}
+	}()
+
+	err := fn(fnCtx)
+	lost := handle.IsLost()
+
+	unlockCtx, unlockCancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer unlockCancel()
+	if uerr := handle.UnLock(unlockCtx); uerr != nil {
+		logger.Error("[distributeMutex] unlock %s err = %s", handle.Key(), uerr.Error())
+	}
+
+	if lost {
+		return DistributeMutexLostError
+	}
+	return err
+}
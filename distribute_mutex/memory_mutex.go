@@ -0,0 +1,101 @@
+package distribute_mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	MemoryMutexKind = "memory"
+)
+
+var (
+	DistributeMutexNilName error = errors.New("[distributeMutex] mutex name is empty")
+)
+
+var (
+	_ = IMutex(&MemoryMutex{})
+)
+
+// 进程内所有的锁，key为锁名称
+var memoryLocks = struct {
+	sync.Mutex
+	locks map[string]*memoryLock
+}{locks: make(map[string]*memoryLock)}
+
+type memoryLock struct {
+	owner    *MemoryMutex
+	released chan struct{}
+}
+
+// Implementation of distributed lock in process memory
+// 用于单元测试和单节点部署，同一进程内相同名称的 MemoryMutex 互斥，每个实例是一个持有者
+type MemoryMutex struct {
+	name string
+}
+
+// Lock 阻塞获取锁，ctx取消时返回ctx的错误
+func (mutex *MemoryMutex) Lock(ctx context.Context) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	for {
+		released, ok := mutex.acquire()
+		if ok {
+			return nil
+		}
+		select {
+		case <-released:
+		case <-baseCtx.Done():
+			return baseCtx.Err()
+		}
+	}
+}
+
+// TryLock 尝试获取锁，被其他实例持有时返回 ErrLocked
+func (mutex *MemoryMutex) TryLock(ctx context.Context) error {
+	if _, ok := mutex.acquire(); !ok {
+		return ErrLocked
+	}
+	return nil
+}
+
+// UnLock 释放锁，未持有时不做处理
+func (mutex *MemoryMutex) UnLock(ctx context.Context) error {
+	memoryLocks.Lock()
+	defer memoryLocks.Unlock()
+	lock, ok := memoryLocks.locks[mutex.name]
+	if ok && lock.owner == mutex {
+		delete(memoryLocks.locks, mutex.name)
+		close(lock.released)
+	}
+	return nil
+}
+
+func (mutex *MemoryMutex) Kind() string {
+	return MemoryMutexKind
+}
+
+// 获取锁，失败时返回当前持有者释放时关闭的通道
+func (mutex *MemoryMutex) acquire() (<-chan struct{}, bool) {
+	memoryLocks.Lock()
+	defer memoryLocks.Unlock()
+	lock, ok := memoryLocks.locks[mutex.name]
+	if !ok {
+		memoryLocks.locks[mutex.name] = &memoryLock{owner: mutex, released: make(chan struct{})}
+		return nil, true
+	}
+	if lock.owner == mutex {
+		return nil, true
+	}
+	return lock.released, false
+}
+
+func NewMemoryDistributeMutex(name string) (*MemoryMutex, error) {
+	if name == "" {
+		return nil, DistributeMutexNilName
+	}
+	return &MemoryMutex{name: name}, nil
+}
@@ -0,0 +1,20 @@
+package distribute_mutex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryMutex(t *testing.T) {
+	testMutexConformance(t, func(t *testing.T, name string) IMutex {
+		m, err := NewMemoryDistributeMutex(t.Name() + "/" + name)
+		assert.Nil(t, err)
+		return m
+	})
+}
+
+func TestNewMemoryDistributeMutex(t *testing.T) {
+	_, err := NewMemoryDistributeMutex("")
+	assert.Equal(t, DistributeMutexNilName, err)
+}
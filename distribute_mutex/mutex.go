@@ -2,8 +2,13 @@ package distribute_mutex
 
 import (
 	"context"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
 )
 
+// 所有实现的 TryLock 在锁被其他持有者占用时都返回 ErrLocked
+var ErrLocked = concurrency.ErrLocked
+
 type IMutex interface {
 	// block
 	Lock(ctx context.Context) error
@@ -13,3 +18,13 @@ type IMutex interface {
 	// mutex kind
 	Kind() string
 }
+
+// 读写锁，写锁使用 IMutex 的方法
+type IRWMutex interface {
+	IMutex
+	// block
+	RLock(ctx context.Context) error
+	// non-block
+	TryRLock(ctx context.Context) error
+	RUnLock(ctx context.Context) error
+}
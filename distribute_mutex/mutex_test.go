@@ -0,0 +1,88 @@
+package distribute_mutex
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// IMutex 实现的通用测试
+// newMutex 每次调用返回名称为name的锁的一个新的持有者
+func testMutexConformance(t *testing.T, newMutex func(t *testing.T, name string) IMutex) {
+	t.Run("TryLock", func(t *testing.T) {
+		a, b := newMutex(t, "try-lock"), newMutex(t, "try-lock")
+		ctx := context.Background()
+
+		assert.Nil(t, a.TryLock(ctx))
+		assert.Equal(t, ErrLocked, b.TryLock(ctx))
+		assert.Nil(t, a.UnLock(ctx))
+		assert.Nil(t, b.TryLock(ctx))
+		assert.Equal(t, ErrLocked, a.TryLock(ctx))
+		assert.Nil(t, b.UnLock(ctx))
+	})
+
+	t.Run("LockWaitsForUnLock", func(t *testing.T) {
+		a, b := newMutex(t, "lock-wait"), newMutex(t, "lock-wait")
+		ctx := context.Background()
+		assert.Nil(t, a.Lock(ctx))
+
+		acquired := make(chan error, 1)
+		go func() { acquired <- b.Lock(ctx) }()
+
+		select {
+		case <-acquired:
+			t.Fatal("lock acquired while held by another holder")
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		assert.Nil(t, a.UnLock(ctx))
+		select {
+		case err := <-acquired:
+			assert.Nil(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("lock not acquired after unlock")
+		}
+		assert.Nil(t, b.UnLock(ctx))
+	})
+
+	t.Run("LockCanceled", func(t *testing.T) {
+		a, b := newMutex(t, "lock-cancel"), newMutex(t, "lock-cancel")
+		assert.Nil(t, a.Lock(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		assert.NotNil(t, b.Lock(ctx))
+
+		assert.Nil(t, a.UnLock(context.Background()))
+		assert.Nil(t, b.TryLock(context.Background()))
+		assert.Nil(t, b.UnLock(context.Background()))
+	})
+
+	t.Run("MutualExclusion", func(t *testing.T) {
+		var inside, total int32
+		wg := sync.WaitGroup{}
+		for i := 0; i < 5; i++ {
+			m := newMutex(t, "exclusion")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 3; j++ {
+					if !assert.Nil(t, m.Lock(context.Background())) {
+						return
+					}
+					assert.Equal(t, int32(1), atomic.AddInt32(&inside, 1))
+					time.Sleep(5 * time.Millisecond)
+					atomic.AddInt32(&inside, -1)
+					atomic.AddInt32(&total, 1)
+					assert.Nil(t, m.UnLock(context.Background()))
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(15), total)
+	})
+}
@@ -0,0 +1,197 @@
+package distribute_mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/logger"
+	"github.com/Zoxu0928/task-common/tools"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	RedisMutexKind = "redis"
+
+	// 阻塞获取锁时的重试间隔
+	DefaultRedisRetryInterval = 100 * time.Millisecond
+
+	// 锁的最小过期时间，过小时来不及续期
+	MinRedisTTL = time.Second
+)
+
+var (
+	DistributeMutexNilRedisPool error = errors.New("[distributeMutex] redis pool is nil")
+)
+
+var (
+	_ = IMutex(&RedisMutex{})
+)
+
+var (
+	// 只删除自己持有的锁
+	redisUnlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// 只续期自己持有的锁
+	redisRenewScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Implementation of distributed lock with Redis
+// 使用 SET NX PX 获取锁，值为实例的随机标识，释放和续期时通过Lua脚本校验标识
+// 持有锁期间每隔 ttl/3 自动续期，进程退出后锁在ttl后自动过期
+type RedisMutex struct {
+	key   string
+	value string
+	ttl   time.Duration
+	pool  *redis.Pool
+
+	mu    sync.Mutex
+	stop  chan struct{}
+	retry time.Duration
+}
+
+// Lock 阻塞获取锁，每隔 DefaultRedisRetryInterval 重试一次
+func (mutex *RedisMutex) Lock(ctx context.Context) error {
+	baseCtx := context.Background()
+	if ctx != nil {
+		baseCtx = ctx
+	}
+	for {
+		err := mutex.TryLock(baseCtx)
+		if err != ErrLocked {
+			return err
+		}
+		select {
+		case <-time.After(mutex.retry):
+		case <-baseCtx.Done():
+			return baseCtx.Err()
+		}
+	}
+}
+
+// TryLock 尝试获取锁，被其他实例持有时返回 ErrLocked
+func (mutex *RedisMutex) TryLock(ctx context.Context) error {
+	conn := mutex.pool.Get()
+	defer conn.Close()
+
+	reply, err := redis.String(conn.Do("SET", mutex.key, mutex.value, "NX", "PX", int64(mutex.ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		// 自己已持有时续期并返回成功
+		renewed, rerr := redis.Int(redisRenewScript.Do(conn, mutex.key, mutex.value, int64(mutex.ttl/time.Millisecond)))
+		if rerr != nil {
+			return rerr
+		}
+		if renewed == 0 {
+			return ErrLocked
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if reply != "OK" {
+		return ErrLocked
+	}
+	mutex.startRenew()
+	return nil
+}
+
+// UnLock 释放锁，锁已被其他实例持有时不做删除
+func (mutex *RedisMutex) UnLock(ctx context.Context) error {
+	mutex.stopRenew()
+
+	conn := mutex.pool.Get()
+	defer conn.Close()
+	_, err := redisUnlockScript.Do(conn, mutex.key, mutex.value)
+	return err
+}
+
+func (mutex *RedisMutex) Kind() string {
+	return RedisMutexKind
+}
+
+// 自动续期，续期失败说明锁已丢失
+func (mutex *RedisMutex) startRenew() {
+	mutex.mu.Lock()
+	defer mutex.mu.Unlock()
+	if mutex.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	mutex.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(mutex.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			conn := mutex.pool.Get()
+			renewed, err := redis.Int(redisRenewScript.Do(conn, mutex.key, mutex.value, int64(mutex.ttl/time.Millisecond)))
+			conn.Close()
+			if err != nil {
+				logger.Error("[distributeMutex] renew redis lock %s err = %s", mutex.key, err.Error())
+				continue
+			}
+			if renewed == 0 {
+				logger.Warn("[distributeMutex] redis lock %s lost", mutex.key)
+				mutex.mu.Lock()
+				if mutex.stop == stop {
+					close(stop)
+					mutex.stop = nil
+				}
+				mutex.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+func (mutex *RedisMutex) stopRenew() {
+	mutex.mu.Lock()
+	defer mutex.mu.Unlock()
+	if mutex.stop != nil {
+		close(mutex.stop)
+		mutex.stop = nil
+	}
+}
+
+func NewRedisDistributeMutex(pool *redis.Pool, key string, ttl time.Duration) (*RedisMutex, error) {
+	if pool == nil {
+		logger.Error(DistributeMutexNilRedisPool.Error())
+		return nil, DistributeMutexNilRedisPool
+	}
+
+	if key == "" {
+		logger.Error(DistributeMutexNilName.Error())
+		return nil, DistributeMutexNilName
+	}
+
+	if ttl <= 0 {
+		ttl = time.Duration(DefaultTTL) * time.Second
+	}
+	if ttl < MinRedisTTL {
+		ttl = MinRedisTTL
+	}
+
+	return &RedisMutex{
+		key:   key,
+		value: tools.Uuid4(),
+		ttl:   ttl,
+		pool:  pool,
+		retry: DefaultRedisRetryInterval,
+	}, nil
+}
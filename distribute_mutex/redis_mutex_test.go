@@ -0,0 +1,72 @@
+package distribute_mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRedisPool(t *testing.T) (*miniredis.Miniredis, *redis.Pool) {
+	server, err := miniredis.Run()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+		MaxIdle: 8,
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		server.Close()
+	})
+	return server, pool
+}
+
+func TestRedisMutex(t *testing.T) {
+	_, pool := newTestRedisPool(t)
+	testMutexConformance(t, func(t *testing.T, name string) IMutex {
+		m, err := NewRedisDistributeMutex(pool, "test/distribute-mutex/"+name, 3*time.Second)
+		assert.Nil(t, err)
+		return m
+	})
+}
+
+func TestRedisMutex_Expire(t *testing.T) {
+	server, pool := newTestRedisPool(t)
+	ctx := context.Background()
+	a, _ := NewRedisDistributeMutex(pool, "expire", 3*time.Second)
+	b, _ := NewRedisDistributeMutex(pool, "expire", 3*time.Second)
+
+	assert.Nil(t, a.TryLock(ctx))
+	assert.Equal(t, ErrLocked, b.TryLock(ctx))
+
+	// 持有者未续期时锁过期，其他实例可以获取，原持有者释放时不删除
+	server.FastForward(3 * time.Second)
+	assert.Nil(t, b.TryLock(ctx))
+	assert.Nil(t, a.UnLock(ctx))
+	assert.Equal(t, ErrLocked, a.TryLock(ctx))
+	assert.Nil(t, b.UnLock(ctx))
+}
+
+func TestNewRedisDistributeMutex(t *testing.T) {
+	_, err := NewRedisDistributeMutex(nil, "key", 0)
+	assert.Equal(t, DistributeMutexNilRedisPool, err)
+
+	_, err = NewRedisDistributeMutex(&redis.Pool{}, "", 0)
+	assert.Equal(t, DistributeMutexNilName, err)
+
+	m, err := NewRedisDistributeMutex(&redis.Pool{}, "key", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(DefaultTTL)*time.Second, m.ttl)
+
+	// 过期时间过小时使用最小值
+	m, err = NewRedisDistributeMutex(&redis.Pool{}, "key", time.Nanosecond)
+	assert.Nil(t, err)
+	assert.Equal(t, MinRedisTTL, m.ttl)
+}
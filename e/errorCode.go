@@ -18,6 +18,7 @@ var (
 	REAL_NAME_UNAUTHENTICATED    = &ErrorCode{401, "REAL_NAME_UNAUTHENTICATED"}    // 未实名认证
 	PERMISSION_DENIED            = &ErrorCode{403, "PERMISSION_DENIED"}            // 没有权限操作资源
 	NOT_FOUND                    = &ErrorCode{404, "NOT_FOUND"}                    // 不存在
+	METHOD_NOT_ALLOWED           = &ErrorCode{405, "METHOD_NOT_ALLOWED"}           // 路径存在但不支持此请求方式
 	ABORTED                      = &ErrorCode{409, "ABORTED"}                      // 无法锁定资源
 	ALREADY_EXISTS               = &ErrorCode{409, "ALREADY_EXISTS"}               // 资源已存在
 	QUOTA_EXCEEDED               = &ErrorCode{429, "QUOTA_EXCEEDED"}               // 超出配额
@@ -31,3 +32,21 @@ var (
 	UNAVAILABLE                  = &ErrorCode{503, "UNAVAILABLE"}                  // 服务不可达，定位为系统已宕机
 	DEADLINE_EXCEEDED            = &ErrorCode{504, "DEADLINE_EXCEEDED"}            // 请求过于频繁
 )
+
+// 所有错误码，按HTTP状态码排列，用于生成接口文档
+func ErrorCodes() []*ErrorCode {
+	return []*ErrorCode{
+		CHARGE_OVERDUE, CHARGE_ARREAR, INVALID_ARGUMENT, FAILED_PRECONDITION, OUT_OF_RANGE, CONFLICT, DUPLICATE,
+		NO_SESSION, UNAUTHENTICATED, REAL_NAME_UNAUTHENTICATED,
+		PERMISSION_DENIED,
+		NOT_FOUND,
+		METHOD_NOT_ALLOWED,
+		ABORTED, ALREADY_EXISTS,
+		QUOTA_EXCEEDED, ACCOUNT_BALANCE_INSUFFICIENT, RATE_LIMIT,
+		CANCELLED,
+		DATA_LOSS, UNKNOWN, INTERNAL,
+		NOT_IMPLEMENTED,
+		UNAVAILABLE,
+		DEADLINE_EXCEEDED,
+	}
+}
@@ -0,0 +1,247 @@
+package config_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/logger"
+	"github.com/Zoxu0928/task-common/validator"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// 配置中心
+// 将一个struct绑定到etcd的路径前缀下，struct的每个字段对应前缀下的一个key，值为yaml格式
+// key的名称依次取 etcd、toml、yaml、json 标签，都没有时使用字段名称
+// 例如：
+//  type LimitConf struct {
+//      Level string `toml:"level" inList:"debug,info" verf`
+//      Qps   int    `toml:"qps" biggerEq:"1" verf`
+//  }
+//  conf, _ := config_center.NewConfig(client, "/config/task-worker", &LimitConf{Level: "info", Qps: 100})
+//  conf.Subscribe(func(old, new interface{}) { logger.SetLevel(new.(*LimitConf).Level) })
+//  go conf.Watch()
+//  // /config/task-worker/level = debug
+//  // /config/task-worker/qps = 200
+
+var (
+	ConfigNilClientError = errors.New("[config] etcd client is nil")
+	ConfigNilPrefixError = errors.New("[config] etcd prefix is empty")
+	ConfigTargetError    = errors.New("[config] target must be a pointer to struct")
+)
+
+// 配置变化回调，old和new都是绑定struct的指针，不要修改
+type Subscriber func(old, new interface{})
+
+// Config 绑定到etcd路径前缀的配置
+type Config struct {
+	prefix string
+	client *clientv3.Client
+
+	mu          sync.RWMutex
+	typ         reflect.Type
+	defaults    reflect.Value
+	current     interface{}
+	values      map[string][]byte // 当前etcd中的值，key为字段对应的名称
+	subscribers []Subscriber
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewConfig 创建配置，target为绑定struct的指针，其中的值作为默认值，之后修改target不影响配置
+func NewConfig(client *clientv3.Client, prefix string, target interface{}) (*Config, error) {
+	if client == nil {
+		return nil, ConfigNilClientError
+	}
+	if prefix == "" {
+		return nil, ConfigNilPrefixError
+	}
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, ConfigTargetError
+	}
+
+	// 复制一份默认值，不与调用方的struct共用
+	typ := v.Elem().Type()
+	defaults := reflect.New(typ).Elem()
+	defaults.Set(v.Elem())
+	current := reflect.New(typ)
+	current.Elem().Set(defaults)
+
+	c := &Config{
+		prefix:   strings.TrimRight(prefix, "/") + "/",
+		client:   client,
+		typ:      typ,
+		defaults: defaults,
+		current:  current.Interface(),
+		values:   make(map[string][]byte),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// Get 获取当前配置，返回绑定struct的指针，每次变化都会生成新的对象，不要修改
+func (c *Config) Get() interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+// Subscribe 订阅配置变化，回调在监听协程中同步执行
+func (c *Config) Subscribe(fn Subscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = append(c.subscribers, fn)
+}
+
+// Load 从etcd全量加载配置，校验不通过时保持原配置并返回错误
+func (c *Config) Load(ctx context.Context) (int64, error) {
+	resp, err := c.client.Get(ctx, c.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	values := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		values[c.name(kv)] = kv.Value
+	}
+	return resp.Header.Revision, c.apply(values)
+}
+
+// Watch 监听配置变化，阻塞直到 Close
+// 监听断开或版本被压缩时重新全量加载
+func (c *Config) Watch() {
+	for c.ctx.Err() == nil {
+		revision, err := c.Load(c.ctx)
+		if err != nil {
+			logger.Error("[config] load %s err = %s", c.prefix, err.Error())
+			if revision == 0 {
+				c.sleep(time.Second)
+				continue
+			}
+		}
+
+		wch := c.client.Watch(clientv3.WithRequireLeader(c.ctx), c.prefix, clientv3.WithPrefix(), clientv3.WithRev(revision+1))
+		for wresp := range wch {
+			if wresp.CompactRevision != 0 || wresp.Err() != nil {
+				logger.Warn("[config] watch %s interrupted, %v", c.prefix, wresp.Err())
+				break
+			}
+			values := c.snapshot()
+			for _, ev := range wresp.Events {
+				switch ev.Type {
+				case clientv3.EventTypePut:
+					values[c.name(ev.Kv)] = ev.Kv.Value
+				case clientv3.EventTypeDelete:
+					delete(values, c.name(ev.Kv))
+				}
+			}
+			if err := c.apply(values); err != nil {
+				logger.Error("[config] apply %s err = %s", c.prefix, err.Error())
+			}
+		}
+		c.sleep(time.Second)
+	}
+}
+
+// 字段对应的名称
+func (c *Config) name(kv *mvccpb.KeyValue) string {
+	return strings.TrimPrefix(string(kv.Key), c.prefix)
+}
+
+func (c *Config) snapshot() map[string][]byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	values := make(map[string][]byte, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	return values
+}
+
+// 基于默认值生成新配置，校验通过后替换当前配置并通知订阅者
+// etcd中的值总是保存下来，后续的变化基于etcd的最新状态合并
+func (c *Config) apply(values map[string][]byte) error {
+	c.mu.Lock()
+	c.values = values
+	c.mu.Unlock()
+
+	next, err := c.decode(values)
+	if err != nil {
+		return err
+	}
+	if err := validator.Validate(next); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	old := c.current
+	changed := !reflect.DeepEqual(old, next)
+	if changed {
+		c.current = next
+	}
+	subscribers := c.subscribers
+	c.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	logger.Info("[config] %s changed", c.prefix)
+	for _, fn := range subscribers {
+		fn(old, next)
+	}
+	return nil
+}
+
+// 将etcd中的值解析到默认值的副本中
+func (c *Config) decode(values map[string][]byte) (interface{}, error) {
+	next := reflect.New(c.typ)
+	next.Elem().Set(c.defaults)
+
+	for i := 0; i < c.typ.NumField(); i++ {
+		field := c.typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		value, ok := values[fieldName(field)]
+		if !ok {
+			continue
+		}
+		target := reflect.New(field.Type)
+		if err := yaml.Unmarshal(value, target.Interface()); err != nil {
+			return nil, fmt.Errorf("%s%s: %s", c.prefix, fieldName(field), err.Error())
+		}
+		next.Elem().Field(i).Set(target.Elem())
+	}
+	return next.Interface(), nil
+}
+
+// 字段对应的key名称
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"etcd", "toml", "yaml", "json"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func (c *Config) sleep(d time.Duration) {
+	select {
+	case <-c.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// Close 停止监听
+// warning: do not close etcd client because of used by other instances
+func (c *Config) Close() {
+	c.cancel()
+}
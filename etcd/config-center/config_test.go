@@ -0,0 +1,68 @@
+package config_center
+
+import (
+	"testing"
+
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type limitConf struct {
+	Level   string         `toml:"level" inList:"debug,info" verf:""`
+	Qps     int            `toml:"qps" biggerEq:"1" verf:""`
+	Models  []string       `json:"models"`
+	Timeout basic.Duration `etcd:"timeout"`
+}
+
+func TestConfig_Apply(t *testing.T) {
+	target := &limitConf{Level: "info", Qps: 100}
+	c, err := NewConfig(&clientv3.Client{}, "/config/test", target)
+	assert.Nil(t, err)
+
+	// 默认值不受调用方修改影响
+	target.Level = "debug"
+	assert.Equal(t, "info", c.Get().(*limitConf).Level)
+
+	changes := 0
+	c.Subscribe(func(old, new interface{}) {
+		changes++
+	})
+
+	err = c.apply(map[string][]byte{
+		"level":   []byte("debug"),
+		"models":  []byte("[a, b]"),
+		"timeout": []byte("3s"),
+	})
+	assert.Nil(t, err)
+	conf := c.Get().(*limitConf)
+	assert.Equal(t, "debug", conf.Level)
+	assert.Equal(t, 100, conf.Qps)
+	assert.Equal(t, []string{"a", "b"}, conf.Models)
+	assert.Equal(t, "3s", conf.Timeout.String())
+	assert.Equal(t, 1, changes)
+
+	// 校验不通过时保持原配置
+	err = c.apply(map[string][]byte{"qps": []byte("0")})
+	assert.NotNil(t, err)
+	assert.Equal(t, "debug", c.Get().(*limitConf).Level)
+
+	// 删除key后恢复默认值
+	err = c.apply(map[string][]byte{})
+	assert.Nil(t, err)
+	assert.Equal(t, "info", c.Get().(*limitConf).Level)
+	assert.Equal(t, 2, changes)
+
+	// 校验不通过时也保存etcd中的值，后续的变化基于最新的值合并
+	err = c.apply(map[string][]byte{"level": []byte("debug"), "qps": []byte("0")})
+	assert.NotNil(t, err)
+	values := c.snapshot()
+	values["qps"] = []byte("10")
+	assert.Nil(t, c.apply(values))
+	assert.Equal(t, "debug", c.Get().(*limitConf).Level)
+	assert.Equal(t, 10, c.Get().(*limitConf).Qps)
+	assert.Equal(t, "info", c.defaults.Interface().(limitConf).Level)
+
+	_, err = NewConfig(&clientv3.Client{}, "/config/test", limitConf{})
+	assert.Equal(t, ConfigTargetError, err)
+}
@@ -0,0 +1,273 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/global"
+	"github.com/Zoxu0928/task-common/logger"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const DefaultElectionTTL = 15 * time.Second
+
+var (
+	ElectionNilClientError = errors.New("[election] etcd client is nil")
+	ElectionNilPrefixError = errors.New("[election] etcd prefix is empty")
+	ElectionNoLeaderError  = errors.New("[election] no leader")
+	ElectionNotLeaderError = errors.New("[election] not leader")
+)
+
+// Election 基于etcd的选主，同一个prefix下只有一个实例成为leader
+// 例如：
+//
+//	election, _ := etcd.NewElection(client, "/election/billing", hostname, 0)
+//	election.OnElected(func(ctx context.Context) { runJob(ctx) })
+//	go election.Campaign()
+type Election struct {
+	prefix string
+	value  string
+	ttl    int
+	client *Client
+
+	mu         sync.RWMutex
+	election   *concurrency.Election
+	leader     bool
+	termCancel context.CancelFunc
+	onElected  []func(ctx context.Context)
+	onRevoked  []func()
+	started    bool
+	stopped    chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewElection 创建选主，value为当前实例的标识，ttl为会话租约有效期
+// 选主会加入全局资源管理，进程退出时自动放弃leader
+func NewElection(client *Client, prefix, value string, ttl time.Duration) (*Election, error) {
+	if client == nil {
+		return nil, ElectionNilClientError
+	}
+	if prefix == "" {
+		return nil, ElectionNilPrefixError
+	}
+	ttlV := int(ttl.Seconds())
+	if ttlV <= 0 {
+		ttlV = int(DefaultElectionTTL.Seconds())
+	}
+
+	e := &Election{
+		prefix:  strings.TrimRight(prefix, "/"),
+		value:   value,
+		ttl:     ttlV,
+		client:  client,
+		stopped: make(chan struct{}),
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	global.DefaultResourceManager.AddBeforeFree(e)
+	return e, nil
+}
+
+// OnElected 成为leader时的回调，在新的协程中执行，ctx在失去leader时被取消
+func (e *Election) OnElected(fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onElected = append(e.onElected, fn)
+}
+
+// OnRevoked 失去leader时的回调
+func (e *Election) OnRevoked(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onRevoked = append(e.onRevoked, fn)
+}
+
+// IsLeader 当前实例是否是leader
+func (e *Election) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Campaign 参与选主，阻塞直到 Close
+// 会话过期或主动放弃后重新参与选主
+func (e *Election) Campaign() {
+	e.mu.Lock()
+	if e.started {
+		e.mu.Unlock()
+		return
+	}
+	e.started = true
+	e.mu.Unlock()
+	defer close(e.stopped)
+
+	for e.ctx.Err() == nil {
+		session, err := concurrency.NewSession(e.client.Client, concurrency.WithTTL(e.ttl))
+		if err != nil {
+			logger.Error("[election] %s create session err = %s", e.prefix, err.Error())
+			e.sleep(time.Second)
+			continue
+		}
+		election := concurrency.NewElection(session, e.prefix)
+
+		// 阻塞直到成为leader
+		if err := election.Campaign(e.ctx, e.value); err != nil {
+			if e.ctx.Err() == nil {
+				logger.Error("[election] %s campaign err = %s", e.prefix, err.Error())
+			}
+			session.Close()
+			e.sleep(time.Second)
+			continue
+		}
+
+		termCtx := e.elected(election)
+
+		// 等待会话过期、主动放弃或关闭
+		select {
+		case <-session.Done():
+			logger.Warn("[election] %s session expired, lease [%x]", e.prefix, session.Lease())
+		case <-termCtx.Done():
+		case <-e.ctx.Done():
+		}
+		e.revoked()
+		// 关闭会话时撤销租约，leader节点随之删除
+		session.Close()
+	}
+}
+
+// 成为leader
+func (e *Election) elected(election *concurrency.Election) context.Context {
+	termCtx, termCancel := context.WithCancel(e.ctx)
+
+	e.mu.Lock()
+	e.election = election
+	e.leader = true
+	e.termCancel = termCancel
+	callbacks := e.onElected
+	e.mu.Unlock()
+
+	logger.Info("[election] %s elected, value = %s", e.prefix, e.value)
+	for _, fn := range callbacks {
+		go fn(termCtx)
+	}
+	return termCtx
+}
+
+// 失去leader
+func (e *Election) revoked() {
+	e.mu.Lock()
+	if !e.leader {
+		e.mu.Unlock()
+		return
+	}
+	e.leader = false
+	e.election = nil
+	e.termCancel()
+	callbacks := e.onRevoked
+	e.mu.Unlock()
+
+	logger.Info("[election] %s revoked, value = %s", e.prefix, e.value)
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// Resign 放弃leader，之后重新参与选主
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.RLock()
+	election, termCancel := e.election, e.termCancel
+	e.mu.RUnlock()
+	if election == nil {
+		return ElectionNotLeaderError
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	err := election.Resign(ctx)
+	termCancel()
+	return err
+}
+
+// Leader 获取当前leader的标识
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	resp, err := e.client.Get(ctx, e.prefix+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", ElectionNoLeaderError
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Observe 监听leader变化，返回新leader的标识，没有leader时返回空字符串
+// ctx取消时关闭返回的通道
+func (e *Election) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		last := "\x00"
+		for ctx.Err() == nil {
+			wctx, wcancel := context.WithCancel(ctx)
+			wch := e.client.Watch(clientv3.WithRequireLeader(wctx), e.prefix+"/", clientv3.WithPrefix())
+			for {
+				leader, err := e.Leader(ctx)
+				if err != nil && err != ElectionNoLeaderError {
+					break
+				}
+				if leader != last {
+					last = leader
+					select {
+					case ch <- leader:
+					case <-ctx.Done():
+						wcancel()
+						return
+					}
+				}
+				if wresp, ok := <-wch; !ok || wresp.Err() != nil {
+					break
+				}
+			}
+			wcancel()
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	return ch
+}
+
+func (e *Election) sleep(d time.Duration) {
+	select {
+	case <-e.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// Close 放弃leader并停止选主，等待leader节点删除后返回
+// warning: do not close etcd client because of used by other instances
+func (e *Election) Close() {
+	e.cancel()
+
+	e.mu.RLock()
+	started := e.started
+	e.mu.RUnlock()
+	if started {
+		select {
+		case <-e.stopped:
+		case <-time.After(time.Duration(e.ttl) * time.Second):
+			logger.Warn("[election] %s close timeout", e.prefix)
+		}
+	}
+	e.revoked()
+}
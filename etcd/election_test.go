@@ -0,0 +1,45 @@
+package etcd_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd"
+	"github.com/Zoxu0928/task-common/etcd/test-server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElection(t *testing.T) {
+	server := test_server.New(t)
+
+	e1, err := etcd.NewElection(server.Client, "/election/test", "e1", 5*time.Second)
+	assert.Nil(t, err)
+	e2, err := etcd.NewElection(server.Client, "/election/test", "e2", 5*time.Second)
+	assert.Nil(t, err)
+
+	elected := make(chan string, 2)
+	e1.OnElected(func(ctx context.Context) { elected <- "e1" })
+	e2.OnElected(func(ctx context.Context) { elected <- "e2" })
+
+	go e1.Campaign()
+	assert.Equal(t, "e1", <-elected)
+	go e2.Campaign()
+	defer e2.Close()
+
+	leader, err := e2.Leader(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "e1", leader)
+	assert.True(t, e1.IsLeader())
+	assert.False(t, e2.IsLeader())
+
+	// leader关闭后另一个实例成为leader
+	e1.Close()
+	select {
+	case v := <-elected:
+		assert.Equal(t, "e2", v)
+	case <-time.After(10 * time.Second):
+		t.Fatal("e2 not elected")
+	}
+	assert.True(t, e2.IsLeader())
+}
@@ -8,6 +8,7 @@ import (
 const (
 	ServiceRegisterPath = "/services/pcd/middlewares"
 	TaskSubscribePath   = "/tasks/pcd/middlewares"
+	TaskCancelPath      = "/tasks/pcd/cancel"
 )
 
 type Task struct {
@@ -17,3 +18,12 @@ type Task struct {
 	CreatedAt time.Time     `json:"created_at" validate:"required"`
 	Creator   string        `json:"creator"`
 }
+
+// TaskCancel 任务取消信号，写入 TaskCancelPath/{owner}/{ref_id}
+type TaskCancel struct {
+	RefID     string    `json:"ref_id" validate:"required"`
+	Owner     string    `json:"owner" validate:"required"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at" validate:"required"`
+	Canceler  string    `json:"canceler"`
+}
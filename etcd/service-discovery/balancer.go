@@ -0,0 +1,204 @@
+package service_discovery
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 负载均衡策略
+const (
+	BALANCE_ROUND_ROBIN     = "round_robin"     // 轮询
+	BALANCE_LEAST_RUNNING   = "least_running"   // 正在执行的任务数最少
+	BALANCE_CONSISTENT_HASH = "consistent_hash" // 一致性哈希，相同的key选择相同的服务
+
+	// 一致性哈希中每个服务的虚拟节点数
+	defaultHashReplicas = 64
+)
+
+var ErrNoService = errors.New("[service] [balancer] no available service")
+
+// Balancer 负载均衡器，从服务列表中选择一个服务
+// key 用于一致性哈希，其它策略忽略
+type Balancer interface {
+	Pick(services []*Service, key string) *Service
+}
+
+// NewBalancer 根据策略名称创建负载均衡器，未知策略使用轮询
+func NewBalancer(strategy string) Balancer {
+	switch strategy {
+	case BALANCE_LEAST_RUNNING:
+		return &leastRunningBalancer{}
+	case BALANCE_CONSISTENT_HASH:
+		return &consistentHashBalancer{replicas: defaultHashReplicas}
+	default:
+		return &roundRobinBalancer{}
+	}
+}
+
+// 按UUID排序，保证每次选择的顺序一致
+func sortedServices(services []*Service) []*Service {
+	sorted := make([]*Service, len(services))
+	copy(sorted, services)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].UUID < sorted[j].UUID
+	})
+	return sorted
+}
+
+// 轮询
+type roundRobinBalancer struct {
+	next uint64
+}
+
+func (b *roundRobinBalancer) Pick(services []*Service, key string) *Service {
+	if len(services) == 0 {
+		return nil
+	}
+	sorted := sortedServices(services)
+	n := atomic.AddUint64(&b.next, 1) - 1
+	return sorted[n%uint64(len(sorted))]
+}
+
+// 选择正在执行的任务数最少的服务，任务数相同时轮询
+// 没有健康信息的服务排在最后
+type leastRunningBalancer struct {
+	rr roundRobinBalancer
+}
+
+func (b *leastRunningBalancer) Pick(services []*Service, key string) *Service {
+	if len(services) == 0 {
+		return nil
+	}
+	least := -1
+	candidates := make([]*Service, 0, len(services))
+	for _, s := range services {
+		info := s.HealthInfo()
+		if info == nil {
+			continue
+		}
+		if least < 0 || info.TaskRunning < least {
+			least = info.TaskRunning
+			candidates = candidates[:0]
+		}
+		if info.TaskRunning == least {
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = services
+	}
+	return b.rr.Pick(candidates, key)
+}
+
+// 一致性哈希，服务列表不变时复用哈希环
+type consistentHashBalancer struct {
+	replicas int
+
+	mu   sync.RWMutex
+	sign string
+	ring []uint32
+	node map[uint32]*Service
+}
+
+func (b *consistentHashBalancer) Pick(services []*Service, key string) *Service {
+	if len(services) == 0 {
+		return nil
+	}
+	ring, node := b.getRing(services)
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring), func(i int) bool { return ring[i] >= hash })
+	if i == len(ring) {
+		i = 0
+	}
+	return node[ring[i]]
+}
+
+func (b *consistentHashBalancer) getRing(services []*Service) ([]uint32, map[uint32]*Service) {
+	sorted := sortedServices(services)
+	ids := make([]string, len(sorted))
+	for i, s := range sorted {
+		ids[i] = s.UUID
+	}
+	sign := strings.Join(ids, ",")
+
+	b.mu.RLock()
+	if b.sign == sign {
+		defer b.mu.RUnlock()
+		return b.ring, b.node
+	}
+	b.mu.RUnlock()
+
+	ring := make([]uint32, 0, len(sorted)*b.replicas)
+	node := make(map[uint32]*Service, len(sorted)*b.replicas)
+	for _, s := range sorted {
+		for i := 0; i < b.replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(s.UUID + "#" + strconv.Itoa(i)))
+			if _, ok := node[hash]; ok {
+				continue
+			}
+			ring = append(ring, hash)
+			node[hash] = s
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i] < ring[j] })
+
+	b.mu.Lock()
+	b.sign, b.ring, b.node = sign, ring, node
+	b.mu.Unlock()
+	return ring, node
+}
+
+// Resolver 将逻辑服务名解析为服务地址，每个服务名对应一个服务发现
+// 实现 http.ServiceResolver
+type Resolver struct {
+	mu          sync.RWMutex
+	discoveries map[string]*Discovery
+	balancer    Balancer
+}
+
+func NewResolver(balancer Balancer) *Resolver {
+	if balancer == nil {
+		balancer = NewBalancer(BALANCE_ROUND_ROBIN)
+	}
+	return &Resolver{
+		discoveries: make(map[string]*Discovery),
+		balancer:    balancer,
+	}
+}
+
+// AddService 登记一个逻辑服务名及其服务发现
+func (r *Resolver) AddService(name string, d *Discovery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discoveries[name] = d
+}
+
+// Pick 从逻辑服务名对应的健康服务中选择一个
+func (r *Resolver) Pick(name, key string) (*Service, error) {
+	r.mu.RLock()
+	d, ok := r.discoveries[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("[service] [balancer] unknown service %s", name)
+	}
+	s := r.balancer.Pick(d.HealthyServices(), key)
+	if s == nil {
+		return nil, ErrNoService
+	}
+	return s, nil
+}
+
+// Resolve 选择一个服务，返回其访问地址，如 http://10.0.0.1:8080
+func (r *Resolver) Resolve(name, key string) (string, error) {
+	s, err := r.Pick(name, key)
+	if err != nil {
+		return "", err
+	}
+	return s.BaseURL(), nil
+}
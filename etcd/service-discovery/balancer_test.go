@@ -0,0 +1,67 @@
+package service_discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService(uuid string, running int) *Service {
+	s := NewService(nil, nil, uuid, "10.0.0.1", uuid, map[string]string{"http": "http://10.0.0.1:8080/?Action=ServiceHealthCheck"})
+	if running >= 0 {
+		s.healthOf().update(&ServiceHealthInfo{TaskRunning: running, Ready: true}, nil)
+	}
+	return s
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	services := []*Service{newTestService("b", -1), newTestService("a", -1)}
+	b := NewBalancer(BALANCE_ROUND_ROBIN)
+	assert.Equal(t, "a", b.Pick(services, "").UUID)
+	assert.Equal(t, "b", b.Pick(services, "").UUID)
+	assert.Equal(t, "a", b.Pick(services, "").UUID)
+	assert.Nil(t, b.Pick(nil, ""))
+}
+
+func TestLeastRunningBalancer(t *testing.T) {
+	services := []*Service{newTestService("a", 5), newTestService("b", 1), newTestService("c", -1)}
+	b := NewBalancer(BALANCE_LEAST_RUNNING)
+	assert.Equal(t, "b", b.Pick(services, "").UUID)
+	assert.Equal(t, "b", b.Pick(services, "").UUID)
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	services := []*Service{newTestService("a", -1), newTestService("b", -1), newTestService("c", -1)}
+	b := NewBalancer(BALANCE_CONSISTENT_HASH)
+	picked := b.Pick(services, "tenant-1")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, picked.UUID, b.Pick(services, "tenant-1").UUID)
+	}
+
+	// 删除未被选中的服务，同一个key仍然选择原来的服务
+	remain := []*Service{picked}
+	for _, s := range services {
+		if s.UUID != picked.UUID {
+			remain = append(remain, s)
+			break
+		}
+	}
+	assert.Equal(t, picked.UUID, b.Pick(remain, "tenant-1").UUID)
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	d := NewDiscovery(nil, "/services/test")
+	d.reset(nil)
+	r := NewResolver(nil)
+	r.AddService("worker", d)
+
+	_, err := r.Resolve("worker", "")
+	assert.Equal(t, ErrNoService, err)
+	_, err = r.Resolve("unknown", "")
+	assert.NotNil(t, err)
+
+	d.entries["/services/test/a"] = &entry{service: newTestService("a", 0)}
+	url, err := r.Resolve("worker", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://10.0.0.1:8080", url)
+}
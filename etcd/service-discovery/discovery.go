@@ -1 +1,328 @@
 package service_discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/Zoxu0928/task-common/logger"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// 服务变化事件类型
+type EventType int
+
+const (
+	EventAdd EventType = iota + 1
+	EventUpdate
+	EventRemove
+	EventHealthy   // 服务恢复健康
+	EventUnhealthy // 服务健康检查未通过
+)
+
+var eventTypeText = map[EventType]string{
+	EventAdd:       "add",
+	EventUpdate:    "update",
+	EventRemove:    "remove",
+	EventHealthy:   "healthy",
+	EventUnhealthy: "unhealthy",
+}
+
+func (t EventType) String() string {
+	return eventTypeText[t]
+}
+
+// 服务变化回调，remove事件中的service为删除前的快照
+type Callback func(event EventType, service *Service)
+
+// 本地缓存的一条服务注册信息
+type entry struct {
+	raw     []byte
+	service *Service
+}
+
+// Discovery 服务发现
+// 监听服务注册路径，在本地缓存所有已注册的服务，服务变化时触发回调
+type Discovery struct {
+	prefix string
+
+	mu       sync.RWMutex
+	entries  map[string]*entry // key为注册路径
+	synced   chan struct{}
+	syncOnce sync.Once
+
+	cmu       sync.RWMutex
+	callbacks []Callback
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *clientv3.Client
+}
+
+func NewDiscovery(client *clientv3.Client, prefix string) *Discovery {
+	d := &Discovery{
+		prefix:  strings.TrimRight(prefix, "/") + "/",
+		entries: make(map[string]*entry),
+		synced:  make(chan struct{}),
+		client:  client,
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return d
+}
+
+// OnChange 注册服务变化回调，回调在监听协程中同步执行，不要阻塞
+func (d *Discovery) OnChange(cb Callback) {
+	d.cmu.Lock()
+	defer d.cmu.Unlock()
+	d.callbacks = append(d.callbacks, cb)
+}
+
+// Start 开始监听服务注册路径，阻塞直到 Close
+// 监听断开或版本被压缩时重新全量获取，并与本地缓存比对补发变化事件
+func (d *Discovery) Start() {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		default:
+		}
+
+		resp, err := d.client.Get(d.ctx, d.prefix, clientv3.WithPrefix())
+		if err != nil {
+			logger.Error("[service] [discovery] failed get %s, %s", d.prefix, err.Error())
+			d.sleep(time.Second)
+			continue
+		}
+		d.reset(resp.Kvs)
+
+		d.watch(resp.Header.Revision + 1)
+		d.sleep(time.Second)
+	}
+}
+
+// WaitSynced 等待第一次全量获取完成
+func (d *Discovery) WaitSynced(ctx context.Context) error {
+	select {
+	case <-d.synced:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 监听变化，监听中断时返回，由调用方重新全量获取
+func (d *Discovery) watch(revision int64) {
+	wch := d.client.Watch(clientv3.WithRequireLeader(d.ctx), d.prefix, clientv3.WithPrefix(), clientv3.WithRev(revision))
+	for wresp := range wch {
+		if wresp.CompactRevision != 0 {
+			logger.Warn("[service] [discovery] watch %s compacted at revision %d", d.prefix, wresp.CompactRevision)
+			return
+		}
+		if err := wresp.Err(); err != nil {
+			logger.Error("[service] [discovery] watch %s, %s", d.prefix, err.Error())
+			return
+		}
+		for _, ev := range wresp.Events {
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				d.put(string(ev.Kv.Key), ev.Kv.Value)
+			case clientv3.EventTypeDelete:
+				d.remove(string(ev.Kv.Key))
+			}
+		}
+	}
+}
+
+// 使用全量数据替换本地缓存
+func (d *Discovery) reset(kvs []*mvccpb.KeyValue) {
+	exists := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		exists[string(kv.Key)] = struct{}{}
+		d.put(string(kv.Key), kv.Value)
+	}
+
+	d.mu.RLock()
+	removed := make([]string, 0)
+	for key := range d.entries {
+		if _, ok := exists[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, key := range removed {
+		d.remove(key)
+	}
+	d.syncOnce.Do(func() { close(d.synced) })
+}
+
+func (d *Discovery) put(key string, value []byte) {
+	service := &Service{}
+	if err := json.Unmarshal(value, service); err != nil {
+		logger.Error("[service] [discovery] bad service %s, %s", key, err.Error())
+		return
+	}
+
+	d.mu.Lock()
+	old, ok := d.entries[key]
+	if ok && bytes.Equal(old.raw, value) {
+		d.mu.Unlock()
+		return
+	}
+	// 注册信息变化时保留健康检查状态
+	if ok {
+		service.health = old.service.health
+	} else {
+		service.health = newHealthState()
+	}
+	d.entries[key] = &entry{raw: value, service: service}
+	d.mu.Unlock()
+
+	if ok {
+		d.fire(EventUpdate, service)
+	} else {
+		d.fire(EventAdd, service)
+	}
+}
+
+func (d *Discovery) remove(key string) {
+	d.mu.Lock()
+	old, ok := d.entries[key]
+	delete(d.entries, key)
+	d.mu.Unlock()
+
+	if ok {
+		d.fire(EventRemove, old.service)
+	}
+}
+
+func (d *Discovery) fire(event EventType, service *Service) {
+	logger.Info("[service] [discovery] %s service uuid=%s ip=%s", event, service.UUID, service.IP)
+
+	d.cmu.RLock()
+	callbacks := d.callbacks
+	d.cmu.RUnlock()
+
+	for _, cb := range callbacks {
+		cb(event, service)
+	}
+}
+
+// Services 获取所有已注册的服务
+func (d *Discovery) Services() []*Service {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	services := make([]*Service, 0, len(d.entries))
+	for _, v := range d.entries {
+		services = append(services, v.service)
+	}
+	return services
+}
+
+// Service 根据UUID获取服务，不存在时返回nil
+func (d *Discovery) Service(uuid string) *Service {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, v := range d.entries {
+		if v.service.UUID == uuid {
+			return v.service
+		}
+	}
+	return nil
+}
+
+// ServicesByKind 获取支持指定任务类型的服务
+func (d *Discovery) ServicesByKind(kind task.TaskKind) []*Service {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	services := make([]*Service, 0)
+	for _, v := range d.entries {
+		for _, k := range v.service.SupportTaskKinds {
+			if k == kind {
+				services = append(services, v.service)
+				break
+			}
+		}
+	}
+	return services
+}
+
+// HealthyServices 获取所有健康的服务
+func (d *Discovery) HealthyServices() []*Service {
+	return filterHealthy(d.Services())
+}
+
+// HealthyServicesByKind 获取支持指定任务类型并且健康的服务
+func (d *Discovery) HealthyServicesByKind(kind task.TaskKind) []*Service {
+	return filterHealthy(d.ServicesByKind(kind))
+}
+
+func filterHealthy(services []*Service) []*Service {
+	healthy := make([]*Service, 0, len(services))
+	for _, s := range services {
+		if s.Healthy() {
+			healthy = append(healthy, s)
+		}
+	}
+	return healthy
+}
+
+// StartHealthCheck 周期性检查所有服务的健康状态，阻塞直到 Close
+// 服务健康状态变化时触发 EventHealthy 或 EventUnhealthy 回调
+func (d *Discovery) StartHealthCheck(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	for {
+		d.checkHealth()
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// 并发检查所有服务的健康状态
+func (d *Discovery) checkHealth() {
+	wg := sync.WaitGroup{}
+	for _, s := range d.Services() {
+		wg.Add(1)
+		go func(s *Service) {
+			defer wg.Done()
+			before := s.Healthy()
+			ctx, cancel := context.WithTimeout(d.ctx, DefaultHealthCheckTimeout)
+			defer cancel()
+			if err := s.HealthCheckContext(ctx); err != nil {
+				logger.Warn("[service] [discovery] health check uuid=%s ip=%s, %s", s.UUID, s.IP, err.Error())
+			}
+			if after := s.Healthy(); after != before {
+				if after {
+					d.fire(EventHealthy, s)
+				} else {
+					d.fire(EventUnhealthy, s)
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (d *Discovery) sleep(t time.Duration) {
+	select {
+	case <-d.ctx.Done():
+	case <-time.After(t):
+	}
+}
+
+// Close 停止监听
+// warning: do not close etcd client because of used by other instances
+func (d *Discovery) Close() {
+	d.cancel()
+}
@@ -0,0 +1,59 @@
+package service_discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/Zoxu0928/task-common/etcd/test-server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscovery_Watch(t *testing.T) {
+	server := test_server.New(t)
+
+	// 启动前已注册的服务
+	s1 := NewService(server.Client.Client, nil, "host1", "127.0.0.1", "s1", nil)
+	go s1.Register("/services", 3)
+	defer s1.Close()
+	assert.Eventually(t, func() bool { return s1.RegisterState() == RegisterStateRegistered }, 5*time.Second, 10*time.Millisecond)
+
+	d := NewDiscovery(server.Client.Client, "/services")
+	events := make(chan string, 16)
+	d.OnChange(func(event EventType, service *Service) { events <- event.String() + ":" + service.UUID })
+	go d.Start()
+	defer d.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, d.WaitSynced(ctx))
+	assert.Equal(t, "add:s1", <-events)
+
+	// 新注册的服务
+	s2 := NewService(server.Client.Client, []task.TaskKind{task.TaskKindAsyncDemo}, "host2", "127.0.0.2", "s2", nil)
+	go s2.Register("/services", 3)
+	assert.Equal(t, "add:s2", wait(t, events))
+	assert.Equal(t, 2, len(d.Services()))
+	assert.Equal(t, "s2", d.ServicesByKind(task.TaskKindAsyncDemo)[0].UUID)
+
+	// 注册信息变化
+	s2.SetStatus(&ServiceHealthInfo{Ready: false})
+	assert.Equal(t, "update:s2", wait(t, events))
+	assert.Equal(t, 1, len(d.HealthyServices()))
+
+	// 注销
+	s2.Close()
+	assert.Equal(t, "remove:s2", wait(t, events))
+	assert.Nil(t, d.Service("s2"))
+}
+
+func wait(t *testing.T, events chan string) string {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait for event timeout")
+		return ""
+	}
+}
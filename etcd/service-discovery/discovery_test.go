@@ -0,0 +1,59 @@
+package service_discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func serviceKv(prefix string, s *Service) *mvccpb.KeyValue {
+	value, _ := json.Marshal(s)
+	return &mvccpb.KeyValue{Key: []byte(prefix + s.UUID), Value: value}
+}
+
+func TestDiscovery_Reset(t *testing.T) {
+	d := NewDiscovery(nil, "/services/test")
+	events := make(map[EventType][]string)
+	d.OnChange(func(event EventType, service *Service) {
+		events[event] = append(events[event], service.UUID)
+	})
+
+	s1 := &Service{UUID: "s1", IP: "10.0.0.1", SupportTaskKinds: []task.TaskKind{task.TaskKindAsyncDemo}}
+	s2 := &Service{UUID: "s2", IP: "10.0.0.2", SupportTaskKinds: []task.TaskKind{task.TaskKindBilling}}
+	d.reset([]*mvccpb.KeyValue{serviceKv(d.prefix, s1), serviceKv(d.prefix, s2)})
+
+	assert.Equal(t, 2, len(d.Services()))
+	assert.Equal(t, 2, len(events[EventAdd]))
+	assert.Equal(t, "s1", d.ServicesByKind(task.TaskKindAsyncDemo)[0].UUID)
+	assert.Equal(t, 0, len(d.ServicesByKind(task.TaskKindVMMigration)))
+
+	// 重新全量获取时，补发变化与删除事件，未变化的服务不触发事件
+	s1.IP = "10.0.0.11"
+	d.reset([]*mvccpb.KeyValue{serviceKv(d.prefix, s1)})
+
+	assert.Equal(t, []string{"s1"}, events[EventUpdate])
+	assert.Equal(t, []string{"s2"}, events[EventRemove])
+	assert.Equal(t, "10.0.0.11", d.Service("s1").IP)
+	assert.Nil(t, d.Service("s2"))
+
+	d.reset([]*mvccpb.KeyValue{serviceKv(d.prefix, s1)})
+	assert.Equal(t, 1, len(events[EventUpdate]))
+}
+
+func TestDiscovery_DrainWithProbe(t *testing.T) {
+	d := NewDiscovery(nil, "/services/test")
+	s1 := &Service{UUID: "s1", IP: "10.0.0.1", Status: &ServiceHealthInfo{Ready: true}}
+	d.reset([]*mvccpb.KeyValue{serviceKv(d.prefix, s1)})
+	d.Service("s1").healthOf().update(&ServiceHealthInfo{Ready: true, TaskRunning: 2}, nil)
+	assert.Equal(t, 1, len(d.HealthyServices()))
+
+	// 排空时注册信息变为未就绪，保留的检查结果不再使服务保持健康
+	s1.Status = &ServiceHealthInfo{Ready: false}
+	d.reset([]*mvccpb.KeyValue{serviceKv(d.prefix, s1)})
+	assert.Equal(t, false, d.Service("s1").Healthy())
+	assert.Equal(t, 2, d.Service("s1").HealthInfo().TaskRunning)
+	assert.Equal(t, 0, len(d.HealthyServices()))
+}
@@ -1,5 +1,27 @@
 package service_discovery
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// 健康检查的超时时间
+	DefaultHealthCheckTimeout = 3 * time.Second
+	// 连续失败多少次后认为服务不健康
+	MaxHealthCheckFailures = 3
+	// 健康检查响应体的最大长度
+	maxHealthPayloadSize int64 = 1 << 20
+
+	healthClient = &http.Client{}
+)
+
 // 服务健康度检查
 type ServiceHealthInfo struct {
 	TaskTotal   int `json:"task_total"`
@@ -13,5 +35,148 @@ type ServiceHealthInfo struct {
 
 // 主机健康度检查
 type HostHealthInfo struct {
-	// 增加一些负载信息
+	// 系统平均负载
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+	CpuNum int     `json:"cpu_num"`
+
+	// 内存，单位字节
+	MemTotal     uint64 `json:"mem_total"`
+	MemAvailable uint64 `json:"mem_available"`
+
+	// 磁盘，单位字节
+	DiskPath  string `json:"disk_path"`
+	DiskTotal uint64 `json:"disk_total"`
+	DiskFree  uint64 `json:"disk_free"`
+
+	// 当前进程打开的文件数及上限
+	OpenFds int    `json:"open_fds"`
+	MaxFds  uint64 `json:"max_fds"`
+
+	Goroutines  int       `json:"goroutines"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// MemUsedPercent 内存使用率，0-100
+func (h *HostHealthInfo) MemUsedPercent() float64 {
+	return usedPercent(h.MemTotal, h.MemAvailable)
+}
+
+// DiskUsedPercent 磁盘使用率，0-100
+func (h *HostHealthInfo) DiskUsedPercent() float64 {
+	return usedPercent(h.DiskTotal, h.DiskFree)
+}
+
+// LoadScore 主机负载评分，越小负载越低
+// 取单核1分钟负载、内存使用率、文件句柄使用率中的最大值，单位为百分比
+func (h *HostHealthInfo) LoadScore() float64 {
+	score := h.Load1 * 100
+	if h.CpuNum > 0 {
+		score = score / float64(h.CpuNum)
+	}
+	if mem := h.MemUsedPercent(); mem > score {
+		score = mem
+	}
+	if h.MaxFds > 0 {
+		if fds := float64(h.OpenFds) * 100 / float64(h.MaxFds); fds > score {
+			score = fds
+		}
+	}
+	return score
+}
+
+func usedPercent(total, free uint64) float64 {
+	if total == 0 || free > total {
+		return 0
+	}
+	return float64(total-free) * 100 / float64(total)
+}
+
+// LeastLoaded 从服务中选出主机负载最低的一个，没有上报主机负载的服务排在最后
+func LeastLoaded(services []*Service) *Service {
+	var target *Service
+	for _, s := range services {
+		if target == nil || target.Host == nil {
+			target = s
+			continue
+		}
+		if s.Host != nil && s.Host.LoadScore() < target.Host.LoadScore() {
+			target = s
+		}
+	}
+	return target
+}
+
+// 健康检查接口的响应，兼容web服务的标准响应格式 {"requestId":"","result":{}}
+type healthPayload struct {
+	Result *ServiceHealthInfo `json:"result"`
+}
+
+// 服务的健康检查状态
+type healthState struct {
+	mu        sync.RWMutex
+	info      *ServiceHealthInfo
+	failures  int
+	checkedAt time.Time
+	lastErr   error
+}
+
+func newHealthState() *healthState {
+	return &healthState{}
+}
+
+// 记录一次健康检查的结果
+func (h *healthState) update(info *ServiceHealthInfo, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkedAt = time.Now()
+	h.lastErr = err
+	if err != nil {
+		h.failures++
+		return
+	}
+	h.failures = 0
+	h.info = info
+}
+
+// 连续检查失败达到 MaxHealthCheckFailures 次为不健康
+func (h *healthState) healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.failures < MaxHealthCheckFailures
+}
+
+// 请求健康检查地址，解析服务健康信息
+func probe(ctx context.Context, url string) (*ServiceHealthInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := healthClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHealthPayloadSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("health check %s status %d", url, resp.StatusCode)
+	}
+
+	payload := &healthPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, fmt.Errorf("health check %s bad payload, %s", url, err.Error())
+	}
+	if payload.Result != nil {
+		return payload.Result, nil
+	}
+	info := &ServiceHealthInfo{}
+	if err := json.Unmarshal(body, info); err != nil {
+		return nil, fmt.Errorf("health check %s bad payload, %s", url, err.Error())
+	}
+	return info, nil
 }
@@ -0,0 +1,80 @@
+package service_discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_HealthCheck(t *testing.T) {
+	status := http.StatusOK
+	body := `{"requestId":"r1","result":{"task_total":10,"running":2,"task_done":8,"ready":true}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", map[string]string{"http": srv.URL})
+	assert.Nil(t, s.HealthCheck())
+	assert.Equal(t, true, s.Healthy())
+	assert.Equal(t, 2, s.HealthInfo().TaskRunning)
+
+	// 连续失败达到阈值后不健康，保留最近一次成功的健康信息
+	status = http.StatusInternalServerError
+	for i := 0; i < MaxHealthCheckFailures; i++ {
+		assert.NotNil(t, s.HealthCheck())
+	}
+	assert.Equal(t, false, s.Healthy())
+	assert.Equal(t, 2, s.HealthInfo().TaskRunning)
+
+	// 恢复后未就绪的服务也不健康
+	status = http.StatusOK
+	body = `{"ready":false}`
+	assert.Nil(t, s.HealthCheck())
+	failures, _, _ := s.HealthFailures()
+	assert.Equal(t, 0, failures)
+	assert.Equal(t, false, s.Healthy())
+
+	// 没有健康检查地址的服务不检查
+	s2 := NewService(nil, nil, "host", "127.0.0.1", "s2", nil)
+	assert.Nil(t, s2.HealthCheck())
+	assert.Equal(t, true, s2.Healthy())
+}
+
+func TestService_HealthCheckConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"running":1,"ready":true}`))
+	}))
+	defer srv.Close()
+
+	// 未通过构造函数创建的服务并发检查时共用同一个健康检查状态
+	s := &Service{IP: "127.0.0.1", HealthCheckUrls: Urls{"http": srv.URL}}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, s.HealthCheck())
+			s.Healthy()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, s.HealthInfo().TaskRunning)
+}
+
+func TestCollectHostHealthInfo(t *testing.T) {
+	h := CollectHostHealthInfo("")
+	assert.Equal(t, "/", h.DiskPath)
+	assert.True(t, h.CpuNum > 0)
+	assert.True(t, h.Goroutines > 0)
+
+	light := &Service{UUID: "light", Host: &HostHealthInfo{CpuNum: 4, Load1: 0.4}}
+	heavy := &Service{UUID: "heavy", Host: &HostHealthInfo{CpuNum: 4, Load1: 3.2}}
+	unknown := &Service{UUID: "unknown"}
+	assert.Equal(t, "light", LeastLoaded([]*Service{unknown, heavy, light}).UUID)
+	assert.Nil(t, LeastLoaded(nil))
+}
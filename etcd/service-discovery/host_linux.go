@@ -0,0 +1,75 @@
+// +build linux
+
+package service_discovery
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// CollectHostHealthInfo 从/proc采集当前主机的负载信息，采集失败的项保持零值
+// diskPath 为统计磁盘使用量的挂载路径，为空时使用根目录
+func CollectHostHealthInfo(diskPath string) *HostHealthInfo {
+	if diskPath == "" {
+		diskPath = "/"
+	}
+	h := &HostHealthInfo{
+		CpuNum:      runtime.NumCPU(),
+		DiskPath:    diskPath,
+		Goroutines:  runtime.NumGoroutine(),
+		CollectedAt: time.Now(),
+	}
+
+	// 平均负载：/proc/loadavg "0.52 0.58 0.59 1/467 12345"
+	if data, err := ioutil.ReadFile("/proc/loadavg"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) >= 3 {
+			h.Load1, _ = strconv.ParseFloat(fields[0], 64)
+			h.Load5, _ = strconv.ParseFloat(fields[1], 64)
+			h.Load15, _ = strconv.ParseFloat(fields[2], 64)
+		}
+	}
+
+	// 内存：/proc/meminfo "MemTotal:       16303472 kB"
+	if f, err := os.Open("/proc/meminfo"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			v, _ := strconv.ParseUint(fields[1], 10, 64)
+			switch fields[0] {
+			case "MemTotal:":
+				h.MemTotal = v * 1024
+			case "MemAvailable:":
+				h.MemAvailable = v * 1024
+			}
+		}
+		f.Close()
+	}
+
+	// 磁盘
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(diskPath, &stat); err == nil {
+		h.DiskTotal = stat.Blocks * uint64(stat.Bsize)
+		h.DiskFree = stat.Bavail * uint64(stat.Bsize)
+	}
+
+	// 文件句柄
+	if fds, err := ioutil.ReadDir("/proc/self/fd"); err == nil {
+		h.OpenFds = len(fds)
+	}
+	limit := syscall.Rlimit{}
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err == nil {
+		h.MaxFds = limit.Cur
+	}
+
+	return h
+}
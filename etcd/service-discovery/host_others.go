@@ -0,0 +1,18 @@
+// +build !linux
+
+package service_discovery
+
+import (
+	"runtime"
+	"time"
+)
+
+// CollectHostHealthInfo 非linux系统只采集cpu核数和协程数
+func CollectHostHealthInfo(diskPath string) *HostHealthInfo {
+	return &HostHealthInfo{
+		CpuNum:      runtime.NumCPU(),
+		DiskPath:    diskPath,
+		Goroutines:  runtime.NumGoroutine(),
+		CollectedAt: time.Now(),
+	}
+}
@@ -3,25 +3,105 @@ package service_discovery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/Zoxu0928/task-common/logger"
 	"strings"
 	"time"
 
-	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
-// Register 服务注册
+// 主机负载信息的上报周期
+var HostHealthInterval = 30 * time.Second
+
+// 统计磁盘使用量的挂载路径
+var HostDiskPath = "/"
+
+// 注册失败后的重试间隔，每次失败翻倍，不超过最大值
+var (
+	RegisterRetryMin = 1 * time.Second
+	RegisterRetryMax = 30 * time.Second
+)
+
+var ErrLeaseLost = errors.New("[service] [register] lease lost")
+
+// 注册状态
+type RegisterState int
+
+const (
+	RegisterStateInit         RegisterState = iota // 未注册
+	RegisterStateRegistering                       // 正在注册
+	RegisterStateRegistered                        // 注册成功，租约续约中
+	RegisterStateLost                              // 租约丢失或注册失败，等待重试
+	RegisterStateUnregistered                      // 已注销
+)
+
+var registerStateText = map[RegisterState]string{
+	RegisterStateInit:         "init",
+	RegisterStateRegistering:  "registering",
+	RegisterStateRegistered:   "registered",
+	RegisterStateLost:         "lost",
+	RegisterStateUnregistered: "unregistered",
+}
+
+func (s RegisterState) String() string {
+	return registerStateText[s]
+}
+
+// 注册状态变化回调，err为导致状态变化的错误
+type RegisterCallback func(state RegisterState, err error)
+
+// OnRegisterStateChange 注册状态变化回调，回调在注册协程中同步执行，不要阻塞
+func (s *Service) OnRegisterStateChange(cb RegisterCallback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callbacks = append(s.callbacks, cb)
+}
+
+// RegisterState 当前注册状态
+func (s *Service) RegisterState() RegisterState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+func (s *Service) setState(state RegisterState, err error) {
+	s.mu.Lock()
+	if s.state == state {
+		s.mu.Unlock()
+		return
+	}
+	s.state = state
+	callbacks := s.callbacks
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Warn("[service] [register] uuid=%s state=%s, %s", s.UUID, state, err.Error())
+	} else {
+		logger.Info("[service] [register] uuid=%s state=%s", s.UUID, state)
+	}
+	for _, cb := range callbacks {
+		cb(state, err)
+	}
+}
+
+// 注册信息
+func (s *Service) marshal() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncTracker()
+	value, err := json.Marshal(s)
+	return string(value), err
+}
+
+// Register 服务注册，阻塞直到 Unregister
 // prefix 表示路径前缀
-// ttl 心跳周期，单位是秒
+// ttl 租约有效期，单位是秒
+// etcd不可用或租约丢失时按退避间隔重试，不会panic
+// 注册信息中的主机负载信息每隔 HostHealthInterval 更新一次，支持的任务类型或健康信息变化时立即更新
+// 只能注册一次，重复调用或 Close 之后调用时直接返回
 func (s *Service) Register(prefix string, ttl int64) {
 	path := strings.TrimRight(prefix, "/") + "/" + s.UUID
-	value, _ := json.Marshal(s)
-
-	kv := clientv3.NewKV(s.client)
-	lease := clientv3.NewLease(s.client)
-
-	var curLeaseID clientv3.LeaseID = 0
 
 	if ttl <= 0 {
 		ttl = 10
@@ -29,39 +109,143 @@ func (s *Service) Register(prefix string, ttl int64) {
 		ttl = 3
 	}
 
-	var interval = time.Duration(ttl/3) * time.Second
+	registering := false
+	s.registerOnce.Do(func() {
+		registering = true
+		s.mu.Lock()
+		s.path = path
+		s.mu.Unlock()
+	})
+	if !registering {
+		logger.Warn("[service] [register] uuid=%s already registered or closed", s.UUID)
+		return
+	}
+	if s.stopped != nil {
+		defer close(s.stopped)
+	}
+
+	retry := RegisterRetryMin
+	for {
+		if s.ctx.Err() != nil {
+			s.setState(RegisterStateUnregistered, nil)
+			return
+		}
+
+		s.setState(RegisterStateRegistering, nil)
+		leaseID, err := s.grant(path, ttl)
+		if err != nil {
+			s.setState(RegisterStateLost, err)
+			s.sleep(retry)
+			if retry *= 2; retry > RegisterRetryMax {
+				retry = RegisterRetryMax
+			}
+			continue
+		}
+		retry = RegisterRetryMin
+		s.mu.Lock()
+		s.leaseID = leaseID
+		s.mu.Unlock()
+		s.setState(RegisterStateRegistered, nil)
+
+		err = s.keepAlive(path, leaseID)
+		s.mu.Lock()
+		s.leaseID = 0
+		s.mu.Unlock()
+		if s.ctx.Err() != nil {
+			s.revoke(path, leaseID)
+			s.setState(RegisterStateUnregistered, nil)
+			return
+		}
+		s.setState(RegisterStateLost, err)
+	}
+}
+
+// 创建租约并写入注册信息
+func (s *Service) grant(path string, ttl int64) (clientv3.LeaseID, error) {
+	s.mu.Lock()
+	s.Host = CollectHostHealthInfo(HostDiskPath)
+	s.mu.Unlock()
+
+	value, err := s.marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Duration(ttl)*time.Second)
+	defer cancel()
+	leaseResp, err := s.client.Grant(ctx, ttl)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.client.Put(ctx, path, value, clientv3.WithLease(leaseResp.ID)); err != nil {
+		return 0, err
+	}
+	return leaseResp.ID, nil
+}
+
+// 续约租约，租约丢失或更新注册信息失败时返回，由调用方重新注册
+func (s *Service) keepAlive(path string, leaseID clientv3.LeaseID) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	ch, err := s.client.KeepAlive(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(HostHealthInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-s.ctx.Done():
-			if err := lease.Close(); err != nil {
-				logger.Error("[service] [register] failed close lease, %s", err.Error())
+		case <-ctx.Done():
+			return ctx.Err()
+		case resp, ok := <-ch:
+			// 续约流关闭或租约已过期
+			if !ok || resp == nil {
+				return ErrLeaseLost
 			}
-			if _, err := kv.Delete(context.TODO(), path); err != nil {
-				logger.Error("[service] [register] failed delete %s, %s", path, err.Error())
+		case <-ticker.C:
+			s.mu.Lock()
+			s.Host = CollectHostHealthInfo(HostDiskPath)
+			s.mu.Unlock()
+			if err := s.put(ctx, path, leaseID); err != nil {
+				return err
 			}
-			return
-		default:
-		GRANT:
-			if curLeaseID == 0 {
-				leaseResp, err := lease.Grant(context.TODO(), ttl)
-				if err != nil {
-					panic(err)
-				}
-
-				if _, err := kv.Put(context.TODO(), path, string(value), clientv3.WithLease(leaseResp.ID)); err != nil {
-					panic(err)
-
-				}
-				curLeaseID = leaseResp.ID
-			} else { // 续约租约，如果租约已经过期将curLeaseID复位到0重新走创建租约的逻辑
-				if _, err := lease.KeepAliveOnce(context.TODO(), curLeaseID); err == rpctypes.ErrLeaseNotFound {
-					curLeaseID = 0
-					goto GRANT
-				}
+		case <-s.updated:
+			if err := s.put(ctx, path, leaseID); err != nil {
+				return err
 			}
 		}
-		time.Sleep(interval)
+	}
+}
+
+// 使用当前租约更新注册信息
+func (s *Service) put(ctx context.Context, path string, leaseID clientv3.LeaseID) error {
+	value, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Put(ctx, path, value, clientv3.WithLease(leaseID))
+	return err
+}
+
+// 注销：撤销租约并删除注册信息
+func (s *Service) revoke(path string, leaseID clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := s.client.Revoke(ctx, leaseID); err != nil {
+		logger.Error("[service] [register] failed revoke lease, %s", err.Error())
+	}
+	if _, err := s.client.Delete(ctx, path); err != nil {
+		logger.Error("[service] [register] failed delete %s, %s", path, err.Error())
+	}
+}
+
+func (s *Service) sleep(d time.Duration) {
+	select {
+	case <-s.ctx.Done():
+	case <-time.After(d):
 	}
 }
 
@@ -70,3 +254,66 @@ func (s *Service) Register(prefix string, ttl int64) {
 func (s *Service) Unregister() {
 	s.cancel()
 }
+
+// Drain 停机排空：将注册信息中的 Ready 置为false，服务发现方不再向本服务分发任务
+// 已注册时同步写入etcd，设置了任务统计时再停止接收新任务并等待运行中的任务结束
+// NewService 时加入全局资源管理的排空阶段
+func (s *Service) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	s.syncTracker()
+	status := &ServiceHealthInfo{}
+	if s.Status != nil {
+		*status = *s.Status
+	}
+	status.Ready = false
+	s.Status = status
+	path, leaseID, tracker := s.path, s.leaseID, s.tracker
+	s.mu.Unlock()
+
+	var err error
+	if leaseID == 0 {
+		logger.Info("[service] [register] uuid=%s marked not ready", s.UUID)
+	} else if err = s.put(ctx, path, leaseID); err != nil {
+		logger.Warn("[service] [register] uuid=%s mark not ready err = %s", s.UUID, err.Error())
+	} else {
+		logger.Info("[service] [register] uuid=%s marked not ready in registry", s.UUID)
+	}
+
+	if tracker != nil {
+		if drainErr := tracker.Drain(ctx); err == nil {
+			err = drainErr
+		}
+	}
+	return err
+}
+
+// Pending 运行中的任务数，排空阶段的状态接口据此显示进度
+func (s *Service) Pending() int {
+	s.mu.RLock()
+	tracker := s.tracker
+	s.mu.RUnlock()
+	if tracker == nil {
+		return 0
+	}
+	return tracker.Pending()
+}
+
+// Close 注销服务，等待租约撤销后返回，可以加入全局资源管理
+func (s *Service) Close() {
+	s.Unregister()
+
+	// 没有 Register 时直接关闭 stopped，之后的 Register 不再生效
+	s.registerOnce.Do(func() {
+		if s.stopped != nil {
+			close(s.stopped)
+		}
+	})
+	if s.stopped == nil {
+		return
+	}
+	select {
+	case <-s.stopped:
+	case <-time.After(5 * time.Second):
+		logger.Warn("[service] [register] uuid=%s unregister timeout", s.UUID)
+	}
+}
@@ -0,0 +1,81 @@
+package service_discovery
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/Zoxu0928/task-common/etcd/test-server"
+	"github.com/stretchr/testify/assert"
+)
+
+// 从etcd读取注册信息，不存在时返回nil
+func registered(t *testing.T, server *test_server.Server, key string) *Service {
+	resp, err := server.Client.Get(context.Background(), key)
+	assert.Nil(t, err)
+	if len(resp.Kvs) == 0 {
+		return nil
+	}
+	s := &Service{}
+	assert.Nil(t, json.Unmarshal(resp.Kvs[0].Value, s))
+	return s
+}
+
+func TestService_Register(t *testing.T) {
+	server := test_server.New(t)
+	s := NewService(server.Client.Client, []task.TaskKind{task.TaskKindAsyncDemo}, "host", "127.0.0.1", "s1", nil)
+	s.SetStatus(&ServiceHealthInfo{Ready: true})
+	go s.Register("/services", 3)
+
+	assert.Eventually(t, func() bool { return s.RegisterState() == RegisterStateRegistered }, 5*time.Second, 10*time.Millisecond)
+	v := registered(t, server, "/services/s1")
+	assert.NotNil(t, v)
+	assert.Equal(t, "s1", v.UUID)
+	assert.Equal(t, []task.TaskKind{task.TaskKindAsyncDemo}, v.SupportTaskKinds)
+	assert.Equal(t, true, v.Status.Ready)
+	assert.NotNil(t, v.Host)
+
+	// 排空时同步写入 Ready=false
+	assert.Nil(t, s.Drain(context.Background()))
+	assert.Equal(t, false, registered(t, server, "/services/s1").Status.Ready)
+
+	// 重复注册直接返回
+	s.Register("/services", 3)
+	assert.Equal(t, RegisterStateRegistered, s.RegisterState())
+
+	// 注销后删除注册信息
+	s.Close()
+	assert.Equal(t, RegisterStateUnregistered, s.RegisterState())
+	assert.Nil(t, registered(t, server, "/services/s1"))
+}
+
+func TestService_RegisterLeaseLost(t *testing.T) {
+	server := test_server.New(t)
+	s := NewService(server.Client.Client, nil, "host", "127.0.0.1", "s1", nil)
+	states := make(chan RegisterState, 16)
+	s.OnRegisterStateChange(func(state RegisterState, err error) { states <- state })
+	go s.Register("/services", 3)
+	defer s.Close()
+
+	assert.Eventually(t, func() bool { return s.RegisterState() == RegisterStateRegistered }, 5*time.Second, 10*time.Millisecond)
+
+	// 租约被撤销后重新注册
+	s.mu.RLock()
+	leaseID := s.leaseID
+	s.mu.RUnlock()
+	_, err := server.Client.Revoke(context.Background(), leaseID)
+	assert.Nil(t, err)
+
+	expect := []RegisterState{RegisterStateRegistering, RegisterStateRegistered, RegisterStateLost, RegisterStateRegistering, RegisterStateRegistered}
+	for _, state := range expect {
+		select {
+		case got := <-states:
+			assert.Equal(t, state, got)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("wait for state %s timeout", state)
+		}
+	}
+	assert.NotNil(t, registered(t, server, "/services/s1"))
+}
@@ -0,0 +1,135 @@
+package service_discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/global"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_RegisterState(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	states := make([]RegisterState, 0)
+	s.OnRegisterStateChange(func(state RegisterState, err error) {
+		states = append(states, state)
+	})
+
+	s.setState(RegisterStateRegistering, nil)
+	s.setState(RegisterStateRegistered, nil)
+	s.setState(RegisterStateRegistered, nil)
+	assert.Equal(t, []RegisterState{RegisterStateRegistering, RegisterStateRegistered}, states)
+	assert.Equal(t, RegisterStateRegistered, s.RegisterState())
+}
+
+func TestService_SetStatus(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	assert.Equal(t, true, s.Healthy())
+
+	// 注册信息变化时通知注册协程，多次变化只保留一次通知
+	s.SetStatus(&ServiceHealthInfo{Ready: false})
+	s.SetSupportTaskKinds(nil)
+	assert.Equal(t, 1, len(s.updated))
+	assert.Equal(t, false, s.Healthy())
+}
+
+func TestService_Drain(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	s.SetStatus(&ServiceHealthInfo{Ready: true, TaskRunning: 2})
+	assert.Equal(t, true, s.Healthy())
+
+	// 未注册时只修改本地的健康信息
+	assert.Nil(t, s.Drain(context.Background()))
+	assert.Equal(t, &ServiceHealthInfo{Ready: false, TaskRunning: 2}, s.Status)
+	assert.Equal(t, false, s.Healthy())
+
+	// 未注册时直接返回
+	s.Close()
+	assert.NotNil(t, s.ctx.Err())
+}
+
+func TestService_CloseBeforeRegister(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+
+	// Close 之后 Register 直接返回，重复 Close 不会阻塞
+	s.Close()
+	done := make(chan struct{})
+	go func() {
+		s.Register("/services", 3)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("register after close not returned")
+	}
+	s.Close()
+	assert.Equal(t, RegisterStateInit, s.RegisterState())
+}
+
+func TestService_DrainWithProbe(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	s.SetStatus(&ServiceHealthInfo{Ready: true})
+	s.healthOf().update(&ServiceHealthInfo{Ready: true, TaskRunning: 3}, nil)
+	assert.Equal(t, true, s.Healthy())
+
+	// 检查结果仍为就绪，注册信息未就绪时不健康，保留检查结果中的任务数
+	assert.Nil(t, s.Drain(context.Background()))
+	assert.Equal(t, false, s.Healthy())
+	assert.Equal(t, &ServiceHealthInfo{Ready: false, TaskRunning: 3}, s.HealthInfo())
+}
+
+func TestService_SetTaskTracker(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	tracker := task.NewTaskTracker()
+	s.SetTaskTracker(tracker)
+	assert.Nil(t, tracker.Begin("task-1"))
+	assert.Nil(t, tracker.Begin("task-2"))
+	tracker.End("task-2")
+
+	// 注册信息中的任务数取自任务统计
+	_, err := s.marshal()
+	assert.Nil(t, err)
+	assert.Equal(t, &ServiceHealthInfo{Ready: true, TaskTotal: 2, TaskRunning: 1, TaskDone: 1}, s.Status)
+
+	// 摘除之后停止接收新任务，等待运行中的任务结束
+	go func() {
+		for !tracker.Draining() {
+			time.Sleep(time.Millisecond)
+		}
+		tracker.End("task-1")
+	}()
+	assert.Equal(t, 1, s.Pending())
+	assert.Nil(t, s.Drain(context.Background()))
+	assert.Equal(t, 0, s.Pending())
+	assert.Equal(t, &ServiceHealthInfo{Ready: false, TaskTotal: 2, TaskRunning: 1, TaskDone: 1}, s.Status)
+	assert.NotNil(t, tracker.Begin("task-3"))
+}
+
+func TestService_DrainTimeout(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	tracker := task.NewTaskTracker()
+	s.SetTaskTracker(tracker)
+	assert.Nil(t, tracker.Begin("task-1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.Drain(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, "DEADLINE_EXCEEDED", err.(e.ApiError).GetType())
+	assert.Equal(t, false, s.Status.Ready)
+}
+
+func TestNewService_Drainer(t *testing.T) {
+	s := NewService(nil, nil, "host", "127.0.0.1", "s1", nil)
+	tracker := task.NewTaskTracker()
+	s.SetTaskTracker(tracker)
+	assert.Nil(t, tracker.Begin("task-1"))
+
+	// 创建时加入全局资源管理的排空阶段，状态接口中显示运行中的任务数
+	drainers := global.DefaultResourceManager.Status().Drainers
+	assert.Equal(t, global.DrainerStatus{Name: "Service", Pending: 1}, drainers[len(drainers)-1])
+}
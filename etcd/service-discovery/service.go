@@ -3,6 +3,10 @@ package service_discovery
 import (
 	"context"
 	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/Zoxu0928/task-common/global"
+	"net/url"
+	"sync"
+	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
@@ -34,9 +38,33 @@ type Service struct {
 	// 支持的任务类型
 	SupportTaskKinds []task.TaskKind `json:"support_task_kinds"`
 
+	// 主机负载信息，注册时周期性更新
+	Host *HostHealthInfo `json:"host,omitempty"`
+	// 服务自身上报的健康信息，变化时更新注册信息
+	Status *ServiceHealthInfo `json:"status,omitempty"`
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	client *clientv3.Client
+	// 健康检查状态，通过 healthOf 访问
+	health     *healthState
+	healthOnce sync.Once
+
+	// 注册状态
+	mu        sync.RWMutex
+	state     RegisterState
+	callbacks []RegisterCallback
+	// 注册信息变化通知
+	updated chan struct{}
+	// 运行中任务的统计，设置后注册信息中的任务数取自这里
+	tracker *task.TaskTracker
+	// 当前注册路径和租约，注册成功后设置
+	path    string
+	leaseID clientv3.LeaseID
+	// Register 返回时关闭，没有 Register 就 Close 时由 Close 关闭
+	stopped chan struct{}
+	// Register 和 Close 先调用的一方生效：Register 只执行一次，Close 之后的 Register 直接返回
+	registerOnce sync.Once
 }
 
 func NewService(client *clientv3.Client, taskKinds []task.TaskKind, hostname, ip, uuid string, urls map[string]string) *Service {
@@ -47,17 +75,138 @@ func NewService(client *clientv3.Client, taskKinds []task.TaskKind, hostname, ip
 		UUID:             uuid,
 		SupportTaskKinds: taskKinds,
 		client:           client,
+		health:           newHealthState(),
+		updated:          make(chan struct{}, 1),
+		stopped:          make(chan struct{}),
 	}
 	s.ctx, s.cancel = context.WithCancel(context.Background())
+	global.DefaultResourceManager.AddDrainer(s)
 	return s
 }
 
-// todo 待实现
+// SetSupportTaskKinds 更新支持的任务类型，已注册时同步更新注册信息
+func (s *Service) SetSupportTaskKinds(kinds []task.TaskKind) {
+	s.mu.Lock()
+	s.SupportTaskKinds = kinds
+	s.mu.Unlock()
+	s.notifyUpdated()
+}
+
+// SetStatus 更新服务自身的健康信息，已注册时同步更新注册信息
+func (s *Service) SetStatus(status *ServiceHealthInfo) {
+	s.mu.Lock()
+	s.Status = status
+	s.mu.Unlock()
+	s.notifyUpdated()
+}
+
+// SetTaskTracker 设置运行中任务的统计，注册信息中的任务数随之更新，排空阶段等待其中运行中的任务结束
+func (s *Service) SetTaskTracker(tracker *task.TaskTracker) {
+	s.mu.Lock()
+	s.tracker = tracker
+	s.mu.Unlock()
+	s.notifyUpdated()
+}
+
+// 使用任务统计更新健康信息中的任务数，任务统计处于排空阶段时为未就绪，调用方持有 s.mu
+func (s *Service) syncTracker() {
+	if s.tracker == nil {
+		return
+	}
+	status := &ServiceHealthInfo{Ready: true}
+	if s.Status != nil {
+		*status = *s.Status
+	}
+	status.TaskTotal, status.TaskRunning, status.TaskDone = s.tracker.Count()
+	if s.tracker.Draining() {
+		status.Ready = false
+	}
+	s.Status = status
+}
+
+// 通知注册协程更新注册信息
+func (s *Service) notifyUpdated() {
+	if s.updated == nil {
+		return
+	}
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+}
+
+// HealthCheck 请求服务的健康检查地址，优先使用https，并记录检查结果
+// 没有配置健康检查地址的服务不做检查
 func (s *Service) HealthCheck() error {
-	if url := s.URL("http"); url != "" {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultHealthCheckTimeout)
+	defer cancel()
+	return s.HealthCheckContext(ctx)
+}
+
+// HealthCheckContext 同 HealthCheck，超时由ctx控制
+func (s *Service) HealthCheckContext(ctx context.Context) error {
+	url := s.URL("https")
+	if url == "" {
+		url = s.URL("http")
+	}
+	if url == "" {
 		return nil
 	}
-	return nil
+
+	info, err := probe(ctx, url)
+	s.healthOf().update(info, err)
+	return err
+}
+
+// 健康检查状态，未通过构造函数创建的服务在首次访问时初始化
+func (s *Service) healthOf() *healthState {
+	s.healthOnce.Do(func() {
+		if s.health == nil {
+			s.health = newHealthState()
+		}
+	})
+	return s.health
+}
+
+// Healthy 服务是否健康，连续检查失败达到 MaxHealthCheckFailures 次或服务未就绪时为不健康
+func (s *Service) Healthy() bool {
+	if !s.healthOf().healthy() {
+		return false
+	}
+	if info := s.HealthInfo(); info != nil && !info.Ready {
+		return false
+	}
+	return true
+}
+
+// HealthInfo 最近一次检查成功时服务上报的健康信息，没有检查过时使用注册信息中的健康信息
+// 注册信息和检查结果任一方未就绪时为未就绪，例如排空阶段注册信息中的 Ready 为false
+func (s *Service) HealthInfo() *ServiceHealthInfo {
+	s.mu.RLock()
+	status := s.Status
+	s.mu.RUnlock()
+
+	h := s.healthOf()
+	h.mu.RLock()
+	info := h.info
+	h.mu.RUnlock()
+	if info == nil {
+		return status
+	}
+	if status != nil && !status.Ready && info.Ready {
+		merged := *info
+		merged.Ready = false
+		return &merged
+	}
+	return info
+}
+
+// HealthFailures 连续检查失败的次数，以及最近一次检查的时间和错误
+func (s *Service) HealthFailures() (int, time.Time, error) {
+	h := s.healthOf()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.failures, h.checkedAt, h.lastErr
 }
 
 func (s *Service) URL(scheme string) string {
@@ -70,3 +219,16 @@ func (s *Service) URL(scheme string) string {
 	}
 	return url
 }
+
+// BaseURL 服务的访问地址，取健康检查地址的协议和主机部分，优先使用http
+// 没有健康检查地址时使用 http://IP
+func (s *Service) BaseURL() string {
+	for _, scheme := range []string{"http", "https"} {
+		if raw := s.URL(scheme); raw != "" {
+			if u, err := url.Parse(raw); err == nil && u.Host != "" {
+				return u.Scheme + "://" + u.Host
+			}
+		}
+	}
+	return "http://" + s.IP
+}
@@ -0,0 +1,230 @@
+package task_cancel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api/task"
+	"github.com/Zoxu0928/task-common/etcd/protocol"
+	"github.com/Zoxu0928/task-common/logger"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// 取消信号的保留时间，单位是秒，超时后信号自动删除，与任务取消中状态的超时时间一致
+	DefaultSignalTTL = int64(task.CancelingTimeout / time.Second)
+)
+
+var (
+	ErrTaskCanceled = errors.New("[taskCancel] task canceled")
+)
+
+// Executor 任务执行函数，ctx在任务被取消时会被取消，执行函数应尽快退出
+type Executor func(ctx context.Context) error
+
+// Canceler 任务取消信号的发送与接收
+// 发送方通过 Cancel 将取消信号写入任务所属执行者的路径下
+// 执行者通过 Start 监听自己路径下的取消信号，取消对应任务的ctx
+type Canceler struct {
+	// 当前执行者，与 protocol.Task.Owner 一致
+	owner  string
+	prefix string
+	ttl    int64
+
+	mu sync.Mutex
+	// 正在执行的任务
+	running map[string]context.CancelFunc
+	// 任务还未开始执行就收到的取消信号
+	pending map[string]*protocol.TaskCancel
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *clientv3.Client
+}
+
+func NewCanceler(client *clientv3.Client, owner string) *Canceler {
+	c := &Canceler{
+		owner:   owner,
+		prefix:  protocol.TaskCancelPath,
+		ttl:     DefaultSignalTTL,
+		running: make(map[string]context.CancelFunc),
+		pending: make(map[string]*protocol.TaskCancel),
+		client:  client,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+// Cancel 向任务的执行者发送取消信号
+func (c *Canceler) Cancel(ctx context.Context, t *protocol.Task, reason string) error {
+	signal := &protocol.TaskCancel{
+		RefID:     t.RefID,
+		Owner:     t.Owner,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+		Canceler:  c.owner,
+	}
+	value, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+
+	lease, err := c.client.Grant(ctx, c.ttl)
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.Put(ctx, c.signalPath(t.Owner, t.RefID), string(value), clientv3.WithLease(lease.ID)); err != nil {
+		return err
+	}
+	logger.Info("[task] [cancel] send cancel signal, task=%s owner=%s reason=%s", t.RefID, t.Owner, reason)
+	return nil
+}
+
+// Track 登记一个开始执行的任务，返回的ctx在收到取消信号时被取消
+// 任务执行结束后必须调用返回的done
+func (c *Canceler) Track(parent context.Context, refID string) (context.Context, func()) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	c.mu.Lock()
+	c.running[refID] = cancel
+	signal := c.pending[refID]
+	delete(c.pending, refID)
+	c.mu.Unlock()
+
+	// 开始执行前已经收到了取消信号
+	if signal != nil {
+		logger.Info("[task] [cancel] cancel running task=%s canceler=%s reason=%s", signal.RefID, signal.Canceler, signal.Reason)
+		cancel()
+	}
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.running, refID)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// Execute 登记并执行任务，任务被取消时返回 ErrTaskCanceled
+func (c *Canceler) Execute(parent context.Context, refID string, exec Executor) error {
+	ctx, done := c.Track(parent, refID)
+	defer done()
+
+	err := exec(ctx)
+	if ctx.Err() == context.Canceled && (parent == nil || parent.Err() == nil) {
+		return ErrTaskCanceled
+	}
+	return err
+}
+
+// Start 开始监听当前执行者的取消信号，阻塞直到 Close
+func (c *Canceler) Start() {
+	path := c.ownerPath(c.owner)
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		default:
+		}
+
+		// 先处理已经存在的取消信号，再从下一个版本开始监听
+		resp, err := c.client.Get(c.ctx, path, clientv3.WithPrefix())
+		if err != nil {
+			logger.Error("[task] [cancel] failed get %s, %s", path, err.Error())
+			c.sleep(time.Second)
+			continue
+		}
+		for _, kv := range resp.Kvs {
+			c.handle(kv.Key, kv.Value)
+		}
+
+		c.watch(path, resp.Header.Revision+1)
+		c.sleep(time.Second)
+	}
+}
+
+// 监听取消信号，监听中断（如版本被压缩）时返回，由调用方重新获取
+func (c *Canceler) watch(path string, revision int64) {
+	wch := c.client.Watch(clientv3.WithRequireLeader(c.ctx), path, clientv3.WithPrefix(), clientv3.WithRev(revision))
+	for wresp := range wch {
+		if wresp.CompactRevision != 0 {
+			logger.Warn("[task] [cancel] watch %s compacted at revision %d", path, wresp.CompactRevision)
+			return
+		}
+		if err := wresp.Err(); err != nil {
+			logger.Error("[task] [cancel] watch %s, %s", path, err.Error())
+			return
+		}
+		for _, ev := range wresp.Events {
+			if ev.Type == clientv3.EventTypePut {
+				c.handle(ev.Kv.Key, ev.Kv.Value)
+			}
+		}
+	}
+}
+
+// 处理一个取消信号，处理完成后删除信号
+func (c *Canceler) handle(key, value []byte) {
+	signal := &protocol.TaskCancel{}
+	if err := json.Unmarshal(value, signal); err != nil {
+		logger.Error("[task] [cancel] bad cancel signal %s, %s", string(key), err.Error())
+	} else {
+		c.deliver(signal)
+	}
+	if _, err := c.client.Delete(c.ctx, string(key)); err != nil {
+		logger.Error("[task] [cancel] failed delete %s, %s", string(key), err.Error())
+	}
+}
+
+// 取消正在执行的任务，任务还未开始执行时暂存信号，等待 Track 时取消，返回任务是否在执行中
+// 查找执行中的任务与暂存信号在同一个锁内完成，避免与 Track 交错时丢失信号
+func (c *Canceler) deliver(signal *protocol.TaskCancel) bool {
+	c.mu.Lock()
+	cancel, ok := c.running[signal.RefID]
+	if !ok {
+		// 同时清理超过保留时间的信号
+		expired := time.Now().Add(-time.Duration(c.ttl) * time.Second)
+		for refID, v := range c.pending {
+			if v.CreatedAt.Before(expired) {
+				delete(c.pending, refID)
+			}
+		}
+		c.pending[signal.RefID] = signal
+	}
+	c.mu.Unlock()
+
+	if ok {
+		logger.Info("[task] [cancel] cancel running task=%s canceler=%s reason=%s", signal.RefID, signal.Canceler, signal.Reason)
+		cancel()
+	}
+	return ok
+}
+
+func (c *Canceler) sleep(d time.Duration) {
+	select {
+	case <-c.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func (c *Canceler) ownerPath(owner string) string {
+	return strings.TrimRight(c.prefix, "/") + "/" + owner + "/"
+}
+
+func (c *Canceler) signalPath(owner, refID string) string {
+	return c.ownerPath(owner) + refID
+}
+
+// Close 停止监听取消信号
+// warning: do not close etcd client because of used by other instances
+func (c *Canceler) Close() {
+	c.cancel()
+}
@@ -0,0 +1,60 @@
+package task_cancel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd/protocol"
+	"github.com/Zoxu0928/task-common/etcd/test-server"
+	"github.com/stretchr/testify/assert"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestCanceler_Etcd(t *testing.T) {
+	server := test_server.New(t)
+	client := server.Client.Client
+
+	// 启动前已经发送的取消信号，任务开始执行时取消
+	sender := NewCanceler(client, "manager")
+	assert.Nil(t, sender.Cancel(context.Background(), &protocol.Task{RefID: "task-1", Owner: "worker-1"}, "before start"))
+
+	worker := NewCanceler(client, "worker-1")
+	go worker.Start()
+	defer worker.Close()
+
+	assert.Eventually(t, func() bool {
+		worker.mu.Lock()
+		defer worker.mu.Unlock()
+		return worker.pending["task-1"] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	ctx, done := worker.Track(context.Background(), "task-1")
+	assert.Equal(t, context.Canceled, ctx.Err())
+	done()
+
+	// 执行中的任务通过watch收到取消信号
+	result := make(chan error, 1)
+	started := make(chan struct{})
+	go func() {
+		result <- worker.Execute(context.Background(), "task-2", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	<-started
+	assert.Nil(t, sender.Cancel(context.Background(), &protocol.Task{RefID: "task-2", Owner: "worker-1"}, "running"))
+	select {
+	case err := <-result:
+		assert.Equal(t, ErrTaskCanceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not canceled")
+	}
+
+	// 处理完成的信号被删除
+	assert.Eventually(t, func() bool {
+		resp, err := client.Get(context.Background(), worker.ownerPath("worker-1"), clientv3.WithPrefix())
+		return err == nil && resp.Count == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
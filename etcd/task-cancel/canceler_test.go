@@ -0,0 +1,34 @@
+package task_cancel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanceler_Execute(t *testing.T) {
+	c := NewCanceler(nil, "worker-1")
+
+	err := c.Execute(context.Background(), "task-1", func(ctx context.Context) error {
+		assert.Equal(t, true, c.deliver(&protocol.TaskCancel{RefID: "task-1"}))
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.Equal(t, ErrTaskCanceled, err)
+
+	// 任务执行结束后不再登记，信号暂存等待下次执行
+	assert.Equal(t, false, c.deliver(&protocol.TaskCancel{RefID: "task-1", CreatedAt: time.Now()}))
+	assert.Contains(t, c.pending, "task-1")
+}
+
+func TestCanceler_TrackPending(t *testing.T) {
+	c := NewCanceler(nil, "worker-1")
+	c.deliver(&protocol.TaskCancel{RefID: "task-2", CreatedAt: time.Now()})
+
+	ctx, done := c.Track(context.Background(), "task-2")
+	defer done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
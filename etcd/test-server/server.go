@@ -0,0 +1,121 @@
+package test_server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/etcd"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// 单元测试使用的内嵌etcd
+// 在临时目录和随机端口上启动单节点etcd，测试结束后关闭并删除数据目录
+// 例如：
+//
+//	func TestRegister(t *testing.T) {
+//	    server := test_server.New(t)
+//	    s := service_discovery.NewService(server.Client.Client, ...)
+//	}
+
+// 启动的超时时间
+var StartTimeout = 30 * time.Second
+
+type Server struct {
+	// 连接内嵌etcd的客户端，Close 时关闭
+	Client   *etcd.Client
+	Endpoint string
+
+	etcd *embed.Etcd
+	dir  string
+}
+
+// New 启动内嵌etcd，启动失败时测试失败，测试结束时自动关闭
+func New(t testing.TB) *Server {
+	t.Helper()
+	s, err := Start()
+	if err != nil {
+		t.Fatalf("start embed etcd, %s", err.Error())
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Start 启动内嵌etcd，调用方负责 Close
+func Start() (*Server, error) {
+	dir, err := ioutil.TempDir("", "task-common-etcd")
+	if err != nil {
+		return nil, err
+	}
+
+	clientURL, err := freeURL()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	peerURL, err := freeURL()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Name = "test"
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	cfg.LCUrls, cfg.ACUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	cfg.LPUrls, cfg.APUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	s := &Server{Endpoint: clientURL.String(), etcd: e, dir: dir}
+	select {
+	case <-e.Server.ReadyNotify():
+	case err := <-e.Err():
+		s.Close()
+		return nil, err
+	case <-time.After(StartTimeout):
+		s.Close()
+		return nil, fmt.Errorf("embed etcd not ready in %s", StartTimeout)
+	}
+
+	s.Client, err = etcd.NewClient(clientv3.Config{
+		Endpoints:   []string{s.Endpoint},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close 关闭客户端和etcd，删除数据目录
+func (s *Server) Close() {
+	if s.Client != nil {
+		s.Client.Close()
+	}
+	s.etcd.Close()
+	os.RemoveAll(s.dir)
+}
+
+// 获取一个空闲的本地端口
+func freeURL() (*url.URL, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	return url.Parse("http://" + l.Addr().String())
+}
@@ -0,0 +1,18 @@
+package test_server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	s := New(t)
+
+	_, err := s.Client.Put(context.Background(), "/test/key", "value")
+	assert.Nil(t, err)
+	resp, err := s.Client.Get(context.Background(), "/test/key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(resp.Kvs[0].Value))
+}
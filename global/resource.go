@@ -1,6 +1,7 @@
 package global
 
 import (
+	"context"
 	"github.com/Zoxu0928/task-common/logger"
 	"os"
 	"os/signal"
@@ -10,11 +11,14 @@ import (
 	"time"
 )
 
+// 状态的数值不代表阶段的先后，判断阶段时逐个比较状态
+// 运行 -> 排空 -> 停机 -> 终止
 const (
 	STATE_INIT = iota
 	STATE_RUNNING
 	STATE_SHUTTING_DOWN
 	STATE_TERMINATE
+	STATE_DRAINING
 )
 
 // 全局资源管理
@@ -32,17 +36,56 @@ const (
 var DefaultHammerTime time.Duration
 var DisableGracefullyStopped bool
 
+// 排空阶段的最长等待时间，超时后继续关闭资源
+var DefaultDrainTimeout = 60 * time.Second
+
+var stateText = map[uint8]string{
+	STATE_INIT:          "init",
+	STATE_RUNNING:       "running",
+	STATE_DRAINING:      "draining",
+	STATE_SHUTTING_DOWN: "shutting_down",
+	STATE_TERMINATE:     "terminate",
+}
+
 // Resource 所有需要关闭的资源，都要实现的接口
 type Resource interface {
 	Close()
 }
 
+// Drainer 关闭资源之前需要排空的资源，例如：从注册中心摘除、停止接收新任务、等待运行中的任务结束
+// 排空阶段按加入顺序依次执行，ctx在 DefaultDrainTimeout 后到期，到期后应尽快返回
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// 可以报告排空进度的资源，例如：运行中的任务数
+type DrainProgress interface {
+	Pending() int
+}
+
+// 排空阶段中一个资源的状态
+type DrainerStatus struct {
+	Name    string `json:"name"`
+	Done    bool   `json:"done"`
+	Pending int    `json:"pending,omitempty"` // 尚未排空的数量，资源实现 DrainProgress 时有值
+	Error   string `json:"error,omitempty"`
+}
+
+// 资源管理的状态，用于状态接口和日志
+type ManagerStatus struct {
+	State          string          `json:"state"`
+	DrainStartedAt *time.Time      `json:"drain_started_at,omitempty"`
+	DrainDeadline  *time.Time      `json:"drain_deadline,omitempty"`
+	Drainers       []DrainerStatus `json:"drainers,omitempty"`
+}
+
 var DefaultResourceManager = &resourceManager{
 	wg:              sync.WaitGroup{},
 	mu:              &sync.RWMutex{},
 	resourcesBefore: make([]Resource, 0),
 	resources:       make([]Resource, 0),
 	resourcesAfter:  make([]Resource, 0),
+	drainers:        make([]Drainer, 0),
 	state:           STATE_INIT,
 }
 
@@ -52,6 +95,10 @@ type resourceManager struct {
 	resourcesBefore []Resource
 	resources       []Resource
 	resourcesAfter  []Resource
+	drainers        []Drainer
+	drainStatus     []DrainerStatus
+	drainStartedAt  time.Time
+	drainDeadline   time.Time
 	state           uint8
 }
 
@@ -74,6 +121,14 @@ func (srv *resourceManager) Add(res Resource) {
 	srv.resources = append(srv.resources, res)
 }
 
+// AddDrainer 添加一个关闭资源之前需要排空的资源（按加入顺序排空）
+func (srv *resourceManager) AddDrainer(d Drainer) {
+	logger.Info("add drainer - [%s]", resourceName(d))
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.drainers = append(srv.drainers, d)
+}
+
 func (srv *resourceManager) setState(st uint8) {
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
@@ -88,6 +143,40 @@ func (srv *resourceManager) GetState() uint8 {
 	return srv.state
 }
 
+// Serving 是否处于对外服务的阶段，排空、停机和终止阶段为false
+func (srv *resourceManager) Serving() bool {
+	switch srv.GetState() {
+	case STATE_INIT, STATE_RUNNING:
+		return true
+	}
+	return false
+}
+
+// Status 当前状态、需要排空的资源以及排空阶段的进度
+func (srv *resourceManager) Status() *ManagerStatus {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+
+	status := &ManagerStatus{State: stateText[srv.state]}
+	if !srv.drainStartedAt.IsZero() {
+		startedAt, deadline := srv.drainStartedAt, srv.drainDeadline
+		status.DrainStartedAt = &startedAt
+		status.DrainDeadline = &deadline
+		status.Drainers = append([]DrainerStatus{}, srv.drainStatus...)
+	} else {
+		// 排空开始之前列出已加入的资源
+		for _, d := range srv.drainers {
+			status.Drainers = append(status.Drainers, DrainerStatus{Name: resourceName(d)})
+		}
+	}
+	for i := range status.Drainers {
+		if p, ok := srv.drainers[i].(DrainProgress); ok {
+			status.Drainers[i].Pending = p.Pending()
+		}
+	}
+	return status
+}
+
 // 销毁所有资源
 func (srv *resourceManager) destroy() {
 	if srv.GetState() != STATE_RUNNING {
@@ -95,6 +184,7 @@ func (srv *resourceManager) destroy() {
 		return
 	}
 	srv.wg.Add(1)
+	srv.drain()
 	srv.setState(STATE_SHUTTING_DOWN)
 	srv.resourcesBefore = srv.eachCloseResources(srv.resourcesBefore)
 
@@ -109,6 +199,55 @@ func (srv *resourceManager) destroy() {
 	logger.Info("destroy success!")
 }
 
+// 排空阶段：按加入顺序依次排空，全部完成或超时后返回
+func (srv *resourceManager) drain() {
+	srv.mu.Lock()
+	drainers := srv.drainers
+	srv.state = STATE_DRAINING
+	srv.drainStartedAt = time.Now()
+	srv.drainDeadline = srv.drainStartedAt.Add(DefaultDrainTimeout)
+	srv.drainStatus = make([]DrainerStatus, len(drainers))
+	for i, d := range drainers {
+		srv.drainStatus[i] = DrainerStatus{Name: resourceName(d)}
+	}
+	srv.mu.Unlock()
+
+	if len(drainers) == 0 {
+		return
+	}
+	logger.Info("drain start, timeout %s", DefaultDrainTimeout)
+	ctx, cancel := context.WithDeadline(context.Background(), srv.drainDeadline)
+	defer cancel()
+
+	for i, d := range drainers {
+		name := resourceName(d)
+		logger.Info("drain resource - [%s]", name)
+		err := d.Drain(ctx)
+
+		srv.mu.Lock()
+		srv.drainStatus[i].Done = true
+		if err != nil {
+			srv.drainStatus[i].Error = err.Error()
+		}
+		srv.mu.Unlock()
+
+		if err != nil {
+			logger.Warn("drain resource - [%s] err = %s", name, err.Error())
+		} else {
+			logger.Info("drained resource - [%s]", name)
+		}
+	}
+	logger.Info("drain finished in %s", time.Since(srv.drainStartedAt))
+}
+
+func resourceName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
 // 关闭资源
 func (srv *resourceManager) eachCloseResources(list []Resource) []Resource {
 	for _, v := range list {
@@ -0,0 +1,82 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type drainRecorder struct {
+	name  string
+	order *[]string
+	err   error
+}
+
+func (d *drainRecorder) Drain(ctx context.Context) error {
+	*d.order = append(*d.order, d.name)
+	return d.err
+}
+
+func TestResourceManager_Drain(t *testing.T) {
+	srv := &resourceManager{mu: &sync.RWMutex{}, state: STATE_RUNNING}
+	assert.Nil(t, srv.Status().Drainers)
+
+	order := make([]string, 0)
+	srv.AddDrainer(&drainRecorder{name: "registry", order: &order})
+	srv.AddDrainer(&drainRecorder{name: "tasks", order: &order, err: errors.New("timeout")})
+	assert.Equal(t, []DrainerStatus{{Name: "drainRecorder"}, {Name: "drainRecorder"}}, srv.Status().Drainers)
+	assert.Nil(t, srv.Status().DrainStartedAt)
+	srv.drain()
+
+	assert.Equal(t, []string{"registry", "tasks"}, order)
+	status := srv.Status()
+	assert.Equal(t, "draining", status.State)
+	assert.True(t, status.DrainDeadline.Sub(*status.DrainStartedAt) == DefaultDrainTimeout)
+	assert.Equal(t, []DrainerStatus{
+		{Name: "drainRecorder", Done: true},
+		{Name: "drainRecorder", Done: true, Error: "timeout"},
+	}, status.Drainers)
+	assert.True(t, status.DrainStartedAt.Before(time.Now().Add(time.Second)))
+}
+
+type drainPending struct {
+	pending int
+}
+
+func (d *drainPending) Drain(ctx context.Context) error {
+	d.pending = 0
+	return nil
+}
+
+func (d *drainPending) Pending() int {
+	return d.pending
+}
+
+func TestResourceManager_DrainPending(t *testing.T) {
+	srv := &resourceManager{mu: &sync.RWMutex{}, state: STATE_RUNNING}
+	d := &drainPending{pending: 2}
+	srv.AddDrainer(d)
+	srv.drainStatus = []DrainerStatus{{Name: "drainPending"}}
+	srv.drainStartedAt = time.Now()
+
+	// 排空过程中显示尚未排空的数量
+	assert.Equal(t, 2, srv.Status().Drainers[0].Pending)
+	srv.drain()
+	assert.Equal(t, []DrainerStatus{{Name: "drainPending", Done: true}}, srv.Status().Drainers)
+}
+
+func TestResourceManager_State(t *testing.T) {
+	// 已有状态的数值保持不变，新增的状态追加在最后
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, []int{STATE_INIT, STATE_RUNNING, STATE_SHUTTING_DOWN, STATE_TERMINATE, STATE_DRAINING})
+
+	srv := &resourceManager{mu: &sync.RWMutex{}, state: STATE_RUNNING}
+	assert.True(t, srv.Serving())
+	for _, st := range []uint8{STATE_DRAINING, STATE_SHUTTING_DOWN, STATE_TERMINATE} {
+		srv.setState(st)
+		assert.False(t, srv.Serving())
+	}
+}
@@ -38,6 +38,17 @@ type HttpClient interface {
 	GetTransport() *http.Transport
 }
 
+// 支持服务解析的客户端，CreateHttpClient 创建的客户端实现了该接口
+// 例如：
+//
+//	if c, ok := client.(http.ResolvableClient); ok {
+//	    c.SetServiceResolver(resolver)
+//	}
+type ResolvableClient interface {
+	// 设置服务解析器，设置后请求地址可以使用 service://服务名/路径 的形式
+	SetServiceResolver(resolver ServiceResolver)
+}
+
 // 配置
 type HttpClientConf struct {
 	TimeOut             basic.Duration `yaml:"timeout" toml:"timeout"`
@@ -29,6 +29,7 @@ const (
 type httpClient struct {
 	timeOut   time.Duration
 	transport *http.Transport
+	resolver  ServiceResolver
 }
 
 // 实例化
@@ -71,6 +72,12 @@ func (this *httpClient) GetTransport() *http.Transport {
 // get请求
 func (this *httpClient) Get(requestId, url string, header map[string]string) (string, e.ApiError) {
 
+	// 解析服务地址
+	url, apiErr := this.resolveUrl(requestId, url, header[HEADER_BALANCE_KEY])
+	if apiErr != nil {
+		return "", apiErr
+	}
+
 	logger.Info("%s GET[NORMAL] URL=%s Headers=%s", requestId, url, tools.ToString(header))
 
 	// 创建Request
@@ -83,6 +90,7 @@ func (this *httpClient) Get(requestId, url string, header map[string]string) (st
 	for k, v := range header {
 		request.Header.Set(k, v)
 	}
+	dropBalanceKey(request.Header)
 
 	// 发出请求
 	client := &http.Client{Timeout: this.timeOut, Transport: this.transport}
@@ -98,6 +106,12 @@ func (this *httpClient) Get(requestId, url string, header map[string]string) (st
 // PostForm请求
 func (this *httpClient) PostForm(requestId, _url string, form map[string]interface{}, response interface{}, header map[string]string) e.ApiError {
 
+	// 解析服务地址
+	_url, apiErr := this.resolveUrl(requestId, _url, header[HEADER_BALANCE_KEY])
+	if apiErr != nil {
+		return apiErr
+	}
+
 	logger.Info("%s POST[FORM] URL=%s Headers=%s Param=%s", requestId, _url, tools.ToString(header), form)
 
 	values := url.Values{}
@@ -120,6 +134,7 @@ func (this *httpClient) PostForm(requestId, _url string, form map[string]interfa
 	for k, v := range header {
 		request.Header.Set(k, v)
 	}
+	dropBalanceKey(request.Header)
 
 	// 发出请求
 	client := &http.Client{Timeout: this.timeOut, Transport: this.transport}
@@ -170,6 +185,12 @@ func (this *httpClient) Delete(requestId, url string, request interface{}, respo
 // 执行http请求
 func (this *httpClient) do(method string, requestId, url string, request interface{}, response interface{}, header map[string]string) e.ApiError {
 
+	// 解析服务地址
+	url, apiErr := this.resolveUrl(requestId, url, header[HEADER_BALANCE_KEY])
+	if apiErr != nil {
+		return apiErr
+	}
+
 	// 解析json
 	jsonByte, err := tools.Marshal(request)
 	if err != nil {
@@ -197,6 +218,7 @@ func (this *httpClient) do(method string, requestId, url string, request interfa
 	for k, v := range header {
 		httpRequest.Header.Set(k, v)
 	}
+	dropBalanceKey(httpRequest.Header)
 
 	// 发出请求
 	client := &http.Client{Timeout: this.timeOut, Transport: this.transport}
@@ -325,6 +347,15 @@ func (this *httpClient) RoundTripPost(requestId, url string, header map[string][
 // 转发请求，使用新request body
 func (this *httpClient) roundTripNewBody(method, requestId, url string, header map[string][]string, reqData []byte) (resBody []byte, resStatus int, err e.ApiError) {
 
+	// 解析服务地址
+	balanceKey := ""
+	if v := header[HEADER_BALANCE_KEY]; len(v) > 0 {
+		balanceKey = v[0]
+	}
+	if url, err = this.resolveUrl(requestId, url, balanceKey); err != nil {
+		return
+	}
+
 	logger.Info("%s %s URL=%s Headers=%s Param=%s", requestId, method, url, tools.ToString(header), string(reqData))
 
 	// 创建Request
@@ -352,9 +383,17 @@ func (this *httpClient) roundTripNewBody(method, requestId, url string, header m
 // 转发请求，使用源request
 func (this *httpClient) RoundTrip(requestId, rawurl string, r *http.Request) (resBody []byte, resStatus int, err e.ApiError) {
 
+	// 解析服务地址
+	if strings.HasPrefix(rawurl, SERVICE_SCHEME) {
+		if rawurl, err = this.resolveUrl(requestId, rawurl, r.Header.Get(HEADER_BALANCE_KEY)); err != nil {
+			return
+		}
+		r.URL = nil
+	}
+
 	logger.Info("RoundTrip %s URL=%s", requestId, rawurl)
 
-	if r.URL.Scheme == "" {
+	if r.URL == nil || r.URL.Scheme == "" {
 		rUrl, pErr := url.Parse(rawurl)
 		if pErr != nil {
 			logger.Error("%s ParseUrl Error. %s", requestId, pErr)
@@ -364,6 +403,7 @@ func (this *httpClient) RoundTrip(requestId, rawurl string, r *http.Request) (re
 	}
 
 	// 请求
+	dropBalanceKey(r.Header)
 	response, tripErr := this.transport.RoundTrip(r)
 
 	// 释放资源
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/e"
+)
+
+const (
+	// 以此开头的请求地址使用逻辑服务名，通过服务解析器选择一个服务实例
+	// 例如 service://task-worker/?Action=DescribeTask
+	SERVICE_SCHEME = "service://"
+	// 一致性哈希负载均衡使用的key，未设置时使用requestId，只在本地使用，不会发送到服务端
+	HEADER_BALANCE_KEY = "X-Balance-Key"
+)
+
+// 服务解析器，将逻辑服务名解析为服务地址，如 http://10.0.0.1:8080
+// service_discovery.Resolver 是基于服务发现的实现
+type ServiceResolver interface {
+	Resolve(name, key string) (string, error)
+}
+
+var _ = ResolvableClient(&httpClient{})
+
+// 设置服务解析器
+func (this *httpClient) SetServiceResolver(resolver ServiceResolver) {
+	this.resolver = resolver
+}
+
+// 将逻辑服务名的请求地址解析为实际地址，普通地址原样返回
+func (this *httpClient) resolveUrl(requestId, url, balanceKey string) (string, e.ApiError) {
+	if !strings.HasPrefix(url, SERVICE_SCHEME) {
+		return url, nil
+	}
+	if this.resolver == nil {
+		return "", httpError("未设置服务解析器", requestId, nil)
+	}
+
+	// service://name/path?query
+	rest := url[len(SERVICE_SCHEME):]
+	name, path := rest, ""
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		name, path = rest[:i], rest[i:]
+	}
+	if balanceKey == "" {
+		balanceKey = requestId
+	}
+
+	base, err := this.resolver.Resolve(name, balanceKey)
+	if err != nil {
+		return "", httpError("服务地址解析失败", requestId, e.NewApiError(e.UNAVAILABLE, err.Error(), err))
+	}
+	return strings.TrimRight(base, "/") + path, nil
+}
+
+// 删除只在本地使用的负载均衡header
+func dropBalanceKey(header http.Header) {
+	header.Del(HEADER_BALANCE_KEY)
+}
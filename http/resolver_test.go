@@ -0,0 +1,52 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticResolver struct {
+	base string
+	key  string
+}
+
+func (r *staticResolver) Resolve(name, key string) (string, error) {
+	if name != "task-worker" {
+		return "", errors.New("no service " + name)
+	}
+	r.key = key
+	return r.base, nil
+}
+
+func TestHttpClient_ServiceResolver(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer srv.Close()
+
+	var client HttpClient = CreateHttpClient(nil)
+	resolver := &staticResolver{base: srv.URL + "/"}
+	client.(ResolvableClient).SetServiceResolver(resolver)
+
+	// 负载均衡key用于选择实例，不发送到服务端
+	body, err := client.Get("r1", "service://task-worker/describe", map[string]string{HEADER_BALANCE_KEY: "task-1", "X-Trace": "t1"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"path":"/describe"}`, body)
+	assert.Equal(t, "task-1", resolver.key)
+	assert.Equal(t, "", header.Get(HEADER_BALANCE_KEY))
+	assert.Equal(t, "t1", header.Get("X-Trace"))
+
+	// 未设置key时使用requestId
+	_, _, err = client.RoundTripPost("r2", "service://task-worker/", map[string][]string{}, []byte(`{}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "r2", resolver.key)
+
+	_, err = client.Get("r3", "service://unknown/", nil)
+	assert.NotNil(t, err)
+}
@@ -53,6 +53,23 @@ func InitLogger(cfg *Conf) {
 	}
 }
 
+// 运行时修改日志级别，支持 debug、info、warn、error，其它值忽略
+func SetLevel(lv string) {
+	switch strings.ToLower(lv) {
+	case "debug":
+		setLevel(_DEBUG)
+	case "info":
+		setLevel(_INFO)
+	case "warn":
+		setLevel(_WARN)
+	case "error":
+		setLevel(_ERROR)
+	default:
+		return
+	}
+	Info("logger level changed to %s", lv)
+}
+
 func Debug(text string, format ...interface{}) {
 	defaultlog.debug(text, format...)
 }
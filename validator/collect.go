@@ -0,0 +1,61 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/e"
+)
+
+// 校验错误的收集
+// all为false时遇到第一个错误即结束校验，为true时记录所有未通过校验的字段后继续校验
+type collector struct {
+	all     bool
+	details []map[string]string
+}
+
+// 记录一个校验错误，不需要收集全部错误时返回该错误以结束校验
+// path为json字段路径，写入错误详情的field中
+func (c *collector) add(err error, path string) error {
+	if !c.all {
+		return fieldError(err, path)
+	}
+	detail := map[string]string{K_DETAIL_FIELD: path, K_DETAIL_MESSAGE: err.Error()}
+	if apiErr, ok := err.(e.ApiError); ok {
+		detail[K_DETAIL_MESSAGE] = apiErr.GetMessage()
+		if details := apiErr.GetDetails(); len(details) > 0 {
+			detail[K_DETAIL_RULE] = details[0][K_DETAIL_RULE]
+		}
+	}
+	c.details = append(c.details, detail)
+	return nil
+}
+
+// 对外接口，验证一个Model数据是否合法，并返回所有未通过校验的字段
+// 错误详情中每一项为 {field, rule, message}，field为json字段路径，例如 tags[0].key
+func ValidateAll(model interface{}) error {
+	c := &collector{all: true}
+	if err := defaultValidator.beginValidate(model, getRules(model), c); err != nil {
+		return err
+	}
+	if len(c.details) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(c.details))
+	for _, detail := range c.details {
+		messages = append(messages, detail[K_DETAIL_MESSAGE])
+	}
+	err := e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid parameters: %s", strings.Join(messages, "; ")), nil)
+	err.SetDetails(c.details)
+	return err
+}
+
+// 字段在json中的名称，没有json标签时使用字段名称
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
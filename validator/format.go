@@ -0,0 +1,188 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/tools"
+)
+
+var UUID_REG = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+var HOSTNAME_LABEL_REG = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// 无值的格式标签，需写为 email:"" 的形式，只匹配完整的标签，避免 ip 匹配到 ipv4
+func hasFlag(tag reflect.StructTag, key string) bool {
+	_, ok := tag.Lookup(key)
+	return ok
+}
+
+func (rule *Rule) setFormats(tag reflect.StructTag) {
+	rule.Ipv6 = hasFlag(tag, K_IPV6)
+	rule.Ip = hasFlag(tag, K_IP)
+	rule.Cidr = hasFlag(tag, K_CIDR)
+	rule.Email = hasFlag(tag, K_EMAIL)
+	rule.Url = hasFlag(tag, K_URL)
+	rule.Uuid = hasFlag(tag, K_UUID)
+	rule.Hostname = hasFlag(tag, K_HOSTNAME)
+	rule.Json = hasFlag(tag, K_JSON)
+	rule.TimeLayout = tag.Get(K_TIME_LAYOUT)
+	if tagval := tag.Get(K_CIDR_IN); tagval != "" {
+		for _, cidr := range strings.Split(tagval, ",") {
+			rule.CidrIn = append(rule.CidrIn, strings.TrimSpace(cidr))
+		}
+	}
+}
+
+// 格式校验，支持字符串和字符串数组，数组中的每个元素都需要满足格式
+func checkFormats(rule *Rule, value reflect.Value) error {
+	type format struct {
+		key   string
+		on    bool
+		check func(v string) string
+	}
+	formats := []format{
+		{K_IPV6, rule.Ipv6, checkIpv6},
+		{K_IP, rule.Ip, checkIp},
+		{K_CIDR, rule.Cidr, checkCidr},
+		{K_CIDR_IN, len(rule.CidrIn) > 0, func(v string) string { return checkCidrIn(v, rule.CidrIn) }},
+		{K_EMAIL, rule.Email, checkEmail},
+		{K_URL, rule.Url, checkUrl},
+		{K_UUID, rule.Uuid, checkUuid},
+		{K_HOSTNAME, rule.Hostname, checkHostname},
+		{K_TIME_LAYOUT, rule.TimeLayout != "", func(v string) string { return checkTimeLayout(v, rule.TimeLayout) }},
+		{K_JSON, rule.Json, checkJson},
+	}
+	for _, f := range formats {
+		if !f.on {
+			continue
+		}
+		switch value.Interface().(type) {
+		case []string:
+			for i := 0; i < value.Len(); i++ {
+				val := value.Index(i).String()
+				if msg := f.check(val); msg != "" {
+					return ruleError(f.key, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s[%d] '%s'. %s", rule.Name, i, val, msg), nil))
+				}
+			}
+		case string:
+			if msg := f.check(value.String()); msg != "" {
+				return ruleError(f.key, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'. %s", rule.Name, value.String(), msg), nil))
+			}
+		default:
+			return ruleError(f.key, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s' unsupported data type", rule.Name, tools.ToString(value)), nil))
+		}
+	}
+	return nil
+}
+
+// 以下校验函数在格式正确时返回空字符串，否则返回错误原因
+
+func checkIpv6(v string) string {
+	if net.ParseIP(v) == nil || !strings.Contains(v, ":") {
+		return "Must be an IPv6 address."
+	}
+	return ""
+}
+
+func checkIp(v string) string {
+	if net.ParseIP(v) == nil {
+		return "Must be an IPv4 or IPv6 address."
+	}
+	return ""
+}
+
+func checkCidr(v string) string {
+	ip, ipNet, err := net.ParseCIDR(v)
+	if err != nil {
+		return "Must be a CIDR block such as 10.0.0.0/16."
+	}
+	if !ip.Equal(ipNet.IP) {
+		return fmt.Sprintf("Host bits must be zero, use %s.", ipNet.String())
+	}
+	return ""
+}
+
+// 值可以是ip，也可以是网段，网段需要完全包含在其中一个指定的网段内
+func checkCidrIn(v string, cidrs []string) string {
+	first, last := net.ParseIP(v), net.ParseIP(v)
+	if first == nil {
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return "Must be an IP address or a CIDR block."
+		}
+		first, last = ipNet.IP, lastIp(ipNet)
+	}
+	for _, cidr := range cidrs {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil && ipNet.Contains(first) && ipNet.Contains(last) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Must be within %s.", strings.Join(cidrs, ", "))
+}
+
+// 网段中的最后一个ip
+func lastIp(ipNet *net.IPNet) net.IP {
+	ip := make(net.IP, len(ipNet.IP))
+	for i := range ipNet.IP {
+		ip[i] = ipNet.IP[i] | ^ipNet.Mask[i]
+	}
+	return ip
+}
+
+func checkEmail(v string) string {
+	addr, err := mail.ParseAddress(v)
+	if err != nil || addr.Address != v || addr.Name != "" {
+		return "Must be an email address such as user@example.com."
+	}
+	return ""
+}
+
+func checkUrl(v string) string {
+	u, err := url.ParseRequestURI(v)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "Must be an absolute URL such as https://example.com/path."
+	}
+	return ""
+}
+
+func checkUuid(v string) string {
+	if !UUID_REG.MatchString(v) {
+		return "Must be a UUID such as 2b4f3c4e-8d1a-4c57-9f3e-2a6f5b7c9d01."
+	}
+	return ""
+}
+
+// 主机名总长度不超过253，每段1-63个字母、数字或"-"，不能以"-"开头或结尾
+func checkHostname(v string) string {
+	name := strings.TrimSuffix(v, ".")
+	if name == "" || len(name) > 253 {
+		return "Must be a hostname of 1 to 253 characters."
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !HOSTNAME_LABEL_REG.MatchString(label) {
+			return fmt.Sprintf("Label '%s' must be 1 to 63 letters, digits or hyphens and can not start or end with a hyphen.", label)
+		}
+	}
+	return ""
+}
+
+func checkTimeLayout(v, layout string) string {
+	if err := tools.CheckTimeFormat(layout, v); err != nil {
+		return fmt.Sprintf("Must match time layout '%s'.", layout)
+	}
+	return ""
+}
+
+func checkJson(v string) string {
+	if !json.Valid([]byte(v)) {
+		return "Must be a valid JSON string."
+	}
+	return ""
+}
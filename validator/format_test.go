@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/stretchr/testify/assert"
+)
+
+type formatRequest struct {
+	Ipv6     string   `json:"ipv6" ipv6:"" verf:"" nilable:""`
+	Ip       string   `json:"ip" ip:"" verf:"" nilable:""`
+	Cidr     string   `json:"cidr" cidr:"" verf:"" nilable:""`
+	Subnets  []string `json:"subnets" cidrIn:"10.0.0.0/8,192.168.0.0/16" verf:"" nilable:""`
+	Email    string   `json:"email" email:"" verf:"" nilable:""`
+	Url      string   `json:"url" url:"" verf:"" nilable:""`
+	Uuid     string   `json:"uuid" uuid:"" verf:"" nilable:""`
+	Hostname string   `json:"hostname" hostname:"" verf:"" nilable:""`
+	Time     string   `json:"time" timeLayout:"2006-01-02 15:04:05" verf:"" nilable:""`
+	Json     string   `json:"json" jsonStr:"" verf:"" nilable:""`
+	Name     string   `json:"name" verf:"" nilable:""`
+}
+
+func TestValidate_Formats(t *testing.T) {
+	valid := &formatRequest{
+		Ipv6:     "fe80::1",
+		Ip:       "10.0.0.1",
+		Cidr:     "10.0.0.0/16",
+		Subnets:  []string{"10.1.0.0/16", "192.168.1.1"},
+		Email:    "user@example.com",
+		Url:      "https://example.com/path?a=1",
+		Uuid:     "2b4f3c4e-8d1a-4c57-9f3e-2a6f5b7c9d01",
+		Hostname: "api-1.example.com",
+		Time:     "2024-01-02 03:04:05",
+		Json:     `{"a":[1,2]}`,
+		Name:     "not json",
+	}
+	assert.Nil(t, ValidateAll(valid))
+
+	err := ValidateAll(&formatRequest{
+		Ipv6:     "10.0.0.1",
+		Ip:       "10.0.0.256",
+		Cidr:     "10.0.0.1/16",
+		Subnets:  []string{"10.1.0.0/16", "172.16.0.0/12"},
+		Email:    "Name <user@example.com>",
+		Url:      "example.com/path",
+		Uuid:     "2b4f3c4e8d1a4c579f3e2a6f5b7c9d01",
+		Hostname: "-api.example.com",
+		Time:     "2024-01-02",
+		Json:     `{"a":`,
+	})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, []map[string]string{
+			{K_DETAIL_FIELD: "ipv6", K_DETAIL_RULE: K_IPV6, K_DETAIL_MESSAGE: "Invalid Ipv6 '10.0.0.1'. Must be an IPv6 address."},
+			{K_DETAIL_FIELD: "ip", K_DETAIL_RULE: K_IP, K_DETAIL_MESSAGE: "Invalid Ip '10.0.0.256'. Must be an IPv4 or IPv6 address."},
+			{K_DETAIL_FIELD: "cidr", K_DETAIL_RULE: K_CIDR, K_DETAIL_MESSAGE: "Invalid Cidr '10.0.0.1/16'. Host bits must be zero, use 10.0.0.0/16."},
+			{K_DETAIL_FIELD: "subnets", K_DETAIL_RULE: K_CIDR_IN, K_DETAIL_MESSAGE: "Invalid Subnets[1] '172.16.0.0/12'. Must be within 10.0.0.0/8, 192.168.0.0/16."},
+			{K_DETAIL_FIELD: "email", K_DETAIL_RULE: K_EMAIL, K_DETAIL_MESSAGE: "Invalid Email 'Name <user@example.com>'. Must be an email address such as user@example.com."},
+			{K_DETAIL_FIELD: "url", K_DETAIL_RULE: K_URL, K_DETAIL_MESSAGE: "Invalid Url 'example.com/path'. Must be an absolute URL such as https://example.com/path."},
+			{K_DETAIL_FIELD: "uuid", K_DETAIL_RULE: K_UUID, K_DETAIL_MESSAGE: "Invalid Uuid '2b4f3c4e8d1a4c579f3e2a6f5b7c9d01'. Must be a UUID such as 2b4f3c4e-8d1a-4c57-9f3e-2a6f5b7c9d01."},
+			{K_DETAIL_FIELD: "hostname", K_DETAIL_RULE: K_HOSTNAME, K_DETAIL_MESSAGE: "Invalid Hostname '-api.example.com'. Label '-api' must be 1 to 63 letters, digits or hyphens and can not start or end with a hyphen."},
+			{K_DETAIL_FIELD: "time", K_DETAIL_RULE: K_TIME_LAYOUT, K_DETAIL_MESSAGE: "Invalid Time '2024-01-02'. Must match time layout '2006-01-02 15:04:05'."},
+			{K_DETAIL_FIELD: "json", K_DETAIL_RULE: K_JSON, K_DETAIL_MESSAGE: `Invalid Json '{"a":'. Must be a valid JSON string.`},
+		}, err.(e.ApiError).GetDetails())
+	}
+}
+
+type payloadRequest struct {
+	Payload string `json:"" verf:""`
+}
+
+func TestValidate_FormatFlags(t *testing.T) {
+	// encoding/json 的 json 标签不是格式校验
+	assert.Nil(t, Validate(&payloadRequest{Payload: "not json"}))
+
+	assert.True(t, hasFlag(`json:"payload" jsonStr:""`, K_JSON))
+	assert.False(t, hasFlag(`json:"payload"`, K_JSON))
+	assert.False(t, hasFlag(`ipv4:""`, K_IP))
+}
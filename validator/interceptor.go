@@ -0,0 +1,48 @@
+package validator
+
+import (
+	"reflect"
+
+	"github.com/Zoxu0928/task-common/interceptor"
+)
+
+// 内置参数校验拦截器的名称
+const CUT_NAME = "validator"
+
+// 参数校验拦截器，在执行controller之前校验入参，返回所有未通过校验的字段
+// 使用示例：
+// interceptor.GetDefaultInterceptor().Add(validator.NewPointCut([]string{"*"}, nil))
+type validateCut struct{}
+
+// 创建参数校验拦截器
+func NewPointCut(matchs []string, excludes []string) *interceptor.PointCut {
+	pointCut := interceptor.NewPointCut(CUT_NAME, matchs, excludes, false)
+	pointCut.CutFunc = &validateCut{}
+	return pointCut
+}
+
+func (cut *validateCut) BeforeFunc(ctx interceptor.Context) error {
+	req := ctx.GetRequest()
+	if req == nil {
+		return nil
+	}
+	// 只校验struct入参
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		if reflect.ValueOf(req).IsNil() {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return ValidateAll(req)
+}
+
+func (cut *validateCut) AfterFunc(ctx interceptor.Context) error {
+	return nil
+}
+
+func (cut *validateCut) OnError(ctx interceptor.Context, err error) {
+}
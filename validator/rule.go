@@ -0,0 +1,301 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/logger"
+	"github.com/Zoxu0928/task-common/tools"
+)
+
+// 自定义校验规则
+// value 为字段的值，指针类型的字段已取出指向的值；param 为标签的值
+// 返回的错误不是 e.ApiError 时，按 INVALID_ARGUMENT 返回
+type RuleFunc func(value reflect.Value, param string) error
+
+// 字段上使用的自定义规则
+type CustomRule struct {
+	Name  string //规则名称，即标签名称
+	Param string //标签的值
+}
+
+// 已注册的自定义规则
+var customRules = struct {
+	lck   sync.RWMutex
+	funcs map[string]RuleFunc
+}{funcs: make(map[string]RuleFunc)}
+
+// 内置规则的标签，不允许注册同名的自定义规则
+var builtinRules = map[string]bool{
+	K_VERF: true, K_NILABLE: true, K_LEN: true, K_MIN_LEN: true, K_MAX_LEN: true, K_EQUAL: true,
+	K_BIGGER: true, K_BIGGER_EQ: true, K_LOWER: true, K_LOWER_EQ: true, K_START_WITH: true, K_END_WITH: true,
+	K_REG: true, K_IN_LIST: true, K_IN_LIST2: true, K_UNBASE64_MAX_LEN: true, K_ABS_PATH: true, K_ASCII: true,
+	K_IPV4: true, K_IPV6: true, K_IP: true, K_CIDR: true, K_CIDR_IN: true, K_EMAIL: true, K_URL: true,
+	K_UUID: true, K_HOSTNAME: true, K_TIME_LAYOUT: true, K_JSON: true, K_MIN_SUM_LEN: true, K_MAX_SUM_LEN: true, K_NON_REPEATABLE: true, K_ITEM_MINLEN: true, K_ITEM_MAXLEN: true,
+	K_REQUIRED_IF: true, K_EQ_FIELD: true, K_GT_FIELD: true, K_ONE_OF_REQUIRED: true, K_RULE_REQUIRED: true,
+}
+
+// 按标签名称注册自定义规则，字段上带有 verf 和该标签时生效，例如：
+//
+//	validator.RegisterRule("even", func(value reflect.Value, param string) error {
+//		if value.Int()%2 != 0 {
+//			return fmt.Errorf("%d is not even", value.Int())
+//		}
+//		return nil
+//	})
+//
+//	type Request struct {
+//		Count int `even:"" verf:""`
+//	}
+//
+// model的规则在第一次校验时生成并缓存，需要在校验之前注册
+func RegisterRule(name string, f RuleFunc) {
+	if builtinRules[name] {
+		logger.Error("register validator rule failed. %s is a built-in rule", name)
+		return
+	}
+	customRules.lck.Lock()
+	defer customRules.lck.Unlock()
+	customRules.funcs[name] = f
+	logger.Info("register validator rule -> %s", name)
+}
+
+// 获得自定义规则
+func getRuleFunc(name string) RuleFunc {
+	customRules.lck.RLock()
+	defer customRules.lck.RUnlock()
+	return customRules.funcs[name]
+}
+
+// 字段标签中使用的自定义规则，按名称排序
+func (rule *Rule) setCustoms(tag reflect.StructTag) {
+	customRules.lck.RLock()
+	defer customRules.lck.RUnlock()
+	for name := range customRules.funcs {
+		if param, ok := tag.Lookup(name); ok {
+			rule.Customs = append(rule.Customs, &CustomRule{Name: name, Param: param})
+		}
+	}
+	sort.Slice(rule.Customs, func(i, j int) bool {
+		return rule.Customs[i].Name < rule.Customs[j].Name
+	})
+}
+
+// 执行自定义规则
+func (custom *CustomRule) check(rule *Rule, value reflect.Value) error {
+	f := getRuleFunc(custom.Name)
+	if f == nil {
+		return nil
+	}
+	err := f(value, custom.Param)
+	if err == nil {
+		return nil
+	}
+	apiErr, ok := err.(e.ApiError)
+	if !ok {
+		apiErr = e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s. %s", rule.Name, err.Error()), err)
+	}
+	return ruleError(custom.Name, apiErr)
+}
+
+// 校验引用同级字段的规则：requiredIf、eqField、gtField、oneOfRequired
+// 同级字段按字段名称查找，字段为空时 eqField、gtField 不校验
+func (mvr *modelValidator) validateCross(model reflect.Value, modelType reflect.Type, rules map[string]*Rule, pName, path string, c *collector) error {
+	for i := 0; i < model.NumField(); i++ {
+		fieldType := modelType.Field(i)
+		if fieldType.Anonymous {
+			continue
+		}
+		fieldName := getFieldName(pName, fieldType.Name)
+		rule := rules[fieldName]
+		if rule == nil {
+			continue
+		}
+		if err := mvr.checkCross(rule, model, model.Field(i), pName); err != nil {
+			if err := c.add(err, getFieldName(path, jsonName(fieldType))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (mvr *modelValidator) checkCross(rule *Rule, model, field reflect.Value, pName string) error {
+	empty := isEmpty(field)
+
+	// 字段不可为空时，已经做过非空校验
+	if rule.RequiredIf != "" && rule.Nilable && empty {
+		name, expect := rule.RequiredIf, ""
+		if i := strings.Index(name, "="); i > -1 {
+			name, expect = name[:i], name[i+1:]
+		}
+		other, ok := siblingOf(model, name)
+		if !ok {
+			return unknownFieldError(rule, K_REQUIRED_IF, name)
+		}
+		if !isEmpty(other) && (expect == "" || tools.ToString(indirect(other)) == expect) {
+			cond := "set"
+			if expect != "" {
+				cond = "'" + expect + "'"
+			}
+			return ruleError(K_REQUIRED_IF, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Parameter %s missing when %s is %s", rule.Name, getFieldName(pName, name), cond), nil))
+		}
+	}
+
+	if len(rule.OneOfRequired) > 0 && empty {
+		names := []string{rule.Name}
+		found := false
+		for _, name := range rule.OneOfRequired {
+			name = strings.TrimSpace(name)
+			other, ok := siblingOf(model, name)
+			if !ok {
+				return unknownFieldError(rule, K_ONE_OF_REQUIRED, name)
+			}
+			found = found || !isEmpty(other)
+			names = append(names, getFieldName(pName, name))
+		}
+		if !found {
+			return ruleError(K_ONE_OF_REQUIRED, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("One of %s is required", strings.Join(names, ", ")), nil))
+		}
+	}
+
+	if empty {
+		return nil
+	}
+
+	if rule.EqField != "" {
+		other, ok := siblingOf(model, rule.EqField)
+		if !ok {
+			return unknownFieldError(rule, K_EQ_FIELD, rule.EqField)
+		}
+		if !isEmpty(other) {
+			if cmp, ok := compareValue(indirect(field), indirect(other)); !ok || cmp != 0 {
+				return ruleError(K_EQ_FIELD, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("%s must be equal to %s", rule.Name, getFieldName(pName, rule.EqField)), nil))
+			}
+		}
+	}
+
+	if rule.GtField != "" {
+		other, ok := siblingOf(model, rule.GtField)
+		if !ok {
+			return unknownFieldError(rule, K_GT_FIELD, rule.GtField)
+		}
+		if !isEmpty(other) {
+			cmp, ok := compareValue(indirect(field), indirect(other))
+			if !ok {
+				return ruleError(K_GT_FIELD, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Validator error. %s can not be compared with %s", rule.Name, getFieldName(pName, rule.GtField)), nil))
+			}
+			if cmp <= 0 {
+				return ruleError(K_GT_FIELD, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s must be greater than %s", rule.Name, getFieldName(pName, rule.GtField)), nil))
+			}
+		}
+	}
+	return nil
+}
+
+// 规则中引用的字段不存在
+func unknownFieldError(rule *Rule, key, name string) e.ApiError {
+	return ruleError(key, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Validator error. Parameter %s refers to unknown field %s", rule.Name, name), nil))
+}
+
+// 按名称查找同级字段，包括匿名struct中的字段
+func siblingOf(model reflect.Value, name string) (reflect.Value, bool) {
+	if _, ok := model.Type().FieldByName(name); !ok {
+		return reflect.Value{}, false
+	}
+	return model.FieldByName(name), true
+}
+
+// 取出指针或接口中的值
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v
+}
+
+// 值是否为空，空指针、空字符串、空数组、零值都认为是空
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Invalid:
+		return true
+	}
+	return v.IsZero()
+}
+
+// 比较两个值，支持数字、字符串、布尔、basic.TimeStandard、basic.Day
+// 返回 -1、0、1，类型无法比较时返回false
+func compareValue(a, b reflect.Value) (int, bool) {
+	if t1, ok := timeOf(a); ok {
+		t2, ok := timeOf(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case t1.Before(t2):
+			return -1, true
+		case t1.After(t2):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	f1, ok1 := floatOf(a)
+	f2, ok2 := floatOf(b)
+	if ok1 && ok2 {
+		switch {
+		case f1 < f2:
+			return -1, true
+		case f1 > f2:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+	if a.Kind() == reflect.Bool && b.Kind() == reflect.Bool {
+		if a.Bool() == b.Bool() {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func timeOf(v reflect.Value) (time.Time, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return time.Time{}, false
+	}
+	switch t := v.Interface().(type) {
+	case basic.TimeStandard:
+		return t.Time(), true
+	case basic.Day:
+		return t.Time(), true
+	case time.Time:
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func floatOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return float64(v.Float()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,363 @@
+package validator
+
+import (
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zoxu0928/task-common/basic"
+)
+
+// 导出的JSON Schema版本，与OpenAPI 3.0一致，exclusiveMinimum、exclusiveMaximum为布尔值
+const SCHEMA_DRAFT = "http://json-schema.org/draft-04/schema#"
+
+// JSON Schema，也用作OpenAPI文档中的Schema
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+}
+
+// 按时间格式序列化的类型
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	timeStandardType = reflect.TypeOf(basic.TimeStandard{})
+	timeMsType       = reflect.TypeOf(basic.TimeMs{})
+	dayType          = reflect.TypeOf(basic.Day{})
+	durationType     = reflect.TypeOf(basic.Duration{})
+	fileHeaderType   = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// 对外接口，将一个Model的校验规则导出为JSON Schema，前端表单可以直接使用
+// 嵌套的struct直接展开，引用自身类型的字段只保留类型
+func JsonSchema(model interface{}) *Schema {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s := schemaOf(t, getRules(model), "", map[reflect.Type]bool{})
+	s.Draft = SCHEMA_DRAFT
+	return s
+}
+
+// 字段上的校验规则，没有 verf 标签时返回nil，规则名称为字段名称
+func FieldRule(field reflect.StructField) *Rule {
+	if !hasRule(string(field.Tag)) {
+		return nil
+	}
+	return newRule(field.Name, field)
+}
+
+// Go类型对应的Schema，pName为规则中的字段全名称
+func schemaOf(t reflect.Type, rules map[string]*Rule, pName string, stack map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType, timeStandardType, timeMsType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case dayType:
+		return &Schema{Type: "string", Format: "date"}
+	case durationType:
+		return &Schema{Type: "string", Description: "duration, e.g. 1h30m"}
+	case fileHeaderType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), rules, pName, stack)}
+	case reflect.Map:
+		// 校验不检查map中的值，不使用任何规则
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), nil, "", stack)}
+	case reflect.Struct:
+		s := &Schema{Type: "object"}
+		if stack[t] {
+			return s
+		}
+		stack[t] = true
+		defer delete(stack, t)
+		s.Properties = make(map[string]*Schema)
+		addProperties(s, t, rules, pName, stack)
+		return s
+	}
+	// interface{} 等任意类型
+	return &Schema{}
+}
+
+// struct的属性，匿名struct的属性与json序列化一致，合并到当前struct中
+func addProperties(s *Schema, t reflect.Type, rules map[string]*Rule, pName string, stack map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			continue
+		}
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			addProperties(s, ft, rules, pName, stack)
+			continue
+		}
+
+		fieldName := getFieldName(pName, field.Name)
+		name := jsonName(field)
+		fs := schemaOf(field.Type, rules, fieldName, stack)
+		if rule := rules[fieldName]; rule != nil && rule.ApplySchema(fs) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// 将校验规则转换为Schema的约束，返回字段是否必填
+// Schema只能表达一部分规则，其余的规则写入描述
+func (rule *Rule) ApplySchema(s *Schema) bool {
+	isArray := s.Type == "array"
+	descriptions := make([]string, 0)
+	patterns := make([]string, 0)
+
+	if v, ok := rule.Len.(int); ok {
+		setLength(s, isArray, &v, &v)
+	}
+	if v, ok := rule.MinLen.(int); ok {
+		setLength(s, isArray, &v, nil)
+	}
+	if v, ok := rule.MaxLen.(int); ok {
+		setLength(s, isArray, nil, &v)
+	}
+	if v, ok := rule.Equal.(string); ok {
+		s.Enum = []interface{}{typedValue(s, v)}
+	}
+	if v, ok := floatOfRule(rule.Bigger); ok {
+		s.Minimum, s.ExclusiveMinimum = &v, true
+	}
+	if v, ok := floatOfRule(rule.BiggerEq); ok {
+		s.Minimum, s.ExclusiveMinimum = &v, false
+	}
+	if v, ok := floatOfRule(rule.Lower); ok {
+		s.Maximum, s.ExclusiveMaximum = &v, true
+	}
+	if v, ok := floatOfRule(rule.LowerEq); ok {
+		s.Maximum, s.ExclusiveMaximum = &v, false
+	}
+	if rule.Reg != "" {
+		patterns = append(patterns, rule.Reg)
+	}
+	if rule.StartWith != "" {
+		patterns = append(patterns, "^"+regexp.QuoteMeta(rule.StartWith))
+	}
+	if rule.EndWith != "" {
+		patterns = append(patterns, regexp.QuoteMeta(rule.EndWith)+"$")
+	}
+	if rule.AbsPath {
+		patterns = append(patterns, "^/")
+	}
+	if rule.Ascii {
+		patterns = append(patterns, `^[\x00-\xff]*$`)
+	}
+	if items, ok := rule.InList.([]string); ok {
+		if rule.InList2 {
+			descriptions = append(descriptions, "one of (case insensitive): "+strings.Join(items, ","))
+		} else {
+			enum := make([]interface{}, 0)
+			for _, item := range items {
+				enum = append(enum, typedValue(s, item))
+			}
+			itemsOf(s, isArray).Enum = enum
+		}
+	}
+
+	// 格式
+	formats := []struct {
+		on     bool
+		format string
+	}{
+		{rule.Ipv4, "ipv4"}, {rule.Ipv6, "ipv6"}, {rule.Email, "email"},
+		{rule.Url, "uri"}, {rule.Uuid, "uuid"}, {rule.Hostname, "hostname"},
+	}
+	for _, f := range formats {
+		if f.on {
+			itemsOf(s, isArray).Format = f.format
+		}
+	}
+	if rule.Ip {
+		descriptions = append(descriptions, "IPv4 or IPv6 address")
+	}
+	if rule.Cidr {
+		descriptions = append(descriptions, "CIDR block")
+	}
+	if len(rule.CidrIn) > 0 {
+		descriptions = append(descriptions, "within "+strings.Join(rule.CidrIn, ", "))
+	}
+	if rule.TimeLayout != "" {
+		descriptions = append(descriptions, "time layout "+rule.TimeLayout)
+	}
+	if rule.Json {
+		descriptions = append(descriptions, "JSON string")
+	}
+
+	if rule.NonRepeatable {
+		s.UniqueItems = true
+	}
+	if v, ok := intOfRule(rule.ItemMinLen); ok && isArray && s.Items != nil {
+		s.Items.MinLength = &v
+	}
+	if v, ok := intOfRule(rule.ItemMaxLen); ok && isArray && s.Items != nil {
+		s.Items.MaxLength = &v
+	}
+	if v, ok := rule.MinSumLen.(int); ok {
+		descriptions = append(descriptions, fmt.Sprintf("total length of items >= %d", v))
+	}
+	if v, ok := rule.MaxSumLen.(int); ok {
+		descriptions = append(descriptions, fmt.Sprintf("total length of items <= %d", v))
+	}
+	if v, ok := rule.unBase64MaxLen.(int); ok {
+		descriptions = append(descriptions, fmt.Sprintf("base64 decoded length <= %d", v))
+	}
+
+	// 引用同级字段的规则和自定义规则
+	if rule.RequiredIf != "" {
+		descriptions = append(descriptions, "required when "+rule.RequiredIf)
+	}
+	if rule.EqField != "" {
+		descriptions = append(descriptions, "must be equal to "+rule.EqField)
+	}
+	if rule.GtField != "" {
+		descriptions = append(descriptions, "must be greater than "+rule.GtField)
+	}
+	if len(rule.OneOfRequired) > 0 {
+		descriptions = append(descriptions, "this or one of "+strings.Join(rule.OneOfRequired, ",")+" is required")
+	}
+	for _, custom := range rule.Customs {
+		descriptions = append(descriptions, fmt.Sprintf("%s:%q", custom.Name, custom.Param))
+	}
+
+	// Schema只能有一个pattern，其余的写入描述
+	for i, p := range patterns {
+		if i == 0 {
+			itemsOf(s, isArray).Pattern = p
+		} else {
+			descriptions = append(descriptions, "must match "+p)
+		}
+	}
+	if len(descriptions) > 0 {
+		if s.Description != "" {
+			descriptions = append([]string{s.Description}, descriptions...)
+		}
+		s.Description = strings.Join(descriptions, "; ")
+	}
+	return !rule.Nilable
+}
+
+// 字符串数组的元素约束作用在items上
+func itemsOf(s *Schema, isArray bool) *Schema {
+	if isArray && s.Items != nil {
+		return s.Items
+	}
+	return s
+}
+
+// 字符串为长度约束，数组为元素个数约束
+func setLength(s *Schema, isArray bool, min, max *int) {
+	if isArray {
+		if min != nil {
+			s.MinItems = min
+		}
+		if max != nil {
+			s.MaxItems = max
+		}
+		return
+	}
+	if min != nil {
+		s.MinLength = min
+	}
+	if max != nil {
+		s.MaxLength = max
+	}
+}
+
+// 按Schema的类型转换规则中的值
+func typedValue(s *Schema, v string) interface{} {
+	t := s.Type
+	if t == "array" && s.Items != nil {
+		t = s.Items.Type
+	}
+	switch t {
+	case "integer":
+		if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+func intOfRule(v interface{}) (int, bool) {
+	str, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(str))
+	return i, err == nil
+}
+
+func floatOfRule(v interface{}) (float64, bool) {
+	str, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	return f, err == nil
+}
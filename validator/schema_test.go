@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaItem struct {
+	Key string `json:"key" maxLen:"8" verf:""`
+}
+
+type schemaLabel struct {
+	Name string `json:"name"`
+}
+
+type schemaRequest struct {
+	Name    string        `json:"name" minLen:"1" maxLen:"10" reg:"^[a-z]+$" verf:""`
+	Status  string        `json:"status" inList:"running,stopped" verf:"" nilable:""`
+	Count   int           `json:"count" bigger:"0" lowerEq:"100" verf:""`
+	Ids     []string      `json:"ids" maxLen:"5" itemMaxLen:"3" non-repeatable:"" verf:"" nilable:""`
+	Email   string        `json:"email" email:"" verf:"" nilable:""`
+	Items   []*schemaItem `json:"items" verf:"" nilable:""`
+	Labels  map[string]*schemaLabel
+	Next    *schemaRequest
+	Ignored string `json:"-"`
+}
+
+func TestJsonSchema(t *testing.T) {
+	s := JsonSchema(&schemaRequest{})
+	_, err := json.Marshal(s)
+	assert.Nil(t, err)
+
+	assert.Equal(t, SCHEMA_DRAFT, s.Draft)
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"name", "count"}, s.Required)
+	assert.NotContains(t, s.Properties, "Ignored")
+
+	name := s.Properties["name"]
+	assert.Equal(t, 1, *name.MinLength)
+	assert.Equal(t, 10, *name.MaxLength)
+	assert.Equal(t, "^[a-z]+$", name.Pattern)
+	assert.Equal(t, []interface{}{"running", "stopped"}, s.Properties["status"].Enum)
+
+	count := s.Properties["count"]
+	assert.Equal(t, "integer", count.Type)
+	assert.Equal(t, 0.0, *count.Minimum)
+	assert.True(t, count.ExclusiveMinimum)
+	assert.Equal(t, 100.0, *count.Maximum)
+	assert.False(t, count.ExclusiveMaximum)
+
+	ids := s.Properties["ids"]
+	assert.Equal(t, 5, *ids.MaxItems)
+	assert.Equal(t, 3, *ids.Items.MaxLength)
+	assert.True(t, ids.UniqueItems)
+	assert.Equal(t, "email", s.Properties["email"].Format)
+
+	// 嵌套的struct使用规则中的字段全名称
+	items := s.Properties["items"]
+	assert.Equal(t, []string{"key"}, items.Items.Required)
+	assert.Equal(t, 8, *items.Items.Properties["key"].MaxLength)
+
+	// map中的值不使用顶层同名字段的规则
+	label := s.Properties["Labels"].AdditionalProperties
+	assert.Nil(t, label.Required)
+	assert.Nil(t, label.Properties["name"].MaxLength)
+
+	// 引用自身类型的字段只保留类型
+	assert.Equal(t, "object", s.Properties["Next"].Type)
+	assert.Nil(t, s.Properties["Next"].Properties)
+}
@@ -51,11 +51,32 @@ const (
 	K_ASCII    = "ascii"   //ascii
 	K_IPV4     = "ipv4"    //ipv4
 
+	K_IPV6        = "ipv6"       //ipv6
+	K_IP          = "ip"         //ipv4或ipv6
+	K_CIDR        = "cidr"       //网段，例如 10.0.0.0/16
+	K_CIDR_IN     = "cidrIn"     //ip或网段在指定的网段内，多个网段用","分隔，例如 cidrIn:"10.0.0.0/8,192.168.0.0/16"
+	K_EMAIL       = "email"      //邮箱地址
+	K_URL         = "url"        //带scheme和host的绝对url
+	K_UUID        = "uuid"       //uuid，例如 2b4f3c4e-8d1a-4c57-9f3e-2a6f5b7c9d01
+	K_HOSTNAME    = "hostname"   //主机名，RFC 1123
+	K_TIME_LAYOUT = "timeLayout" //满足时间格式，例如 timeLayout:"2006-01-02 15:04:05"
+	K_JSON        = "jsonStr"    //json字符串
+
 	K_MIN_SUM_LEN    = "minSumLen"      //字符串数组元素所有值总长度最小
 	K_MAX_SUM_LEN    = "maxSumLen"      //字符串数组元素所有值总长度最大
 	K_NON_REPEATABLE = "non-repeatable" //字符串数组不允许元素重复
 	K_ITEM_MINLEN    = "itemMinLen"     //字符串数组中每个元素的最小长度
 	K_ITEM_MAXLEN    = "itemMaxLen"     //字符串数组中每个元素的最大长度
+
+	K_REQUIRED_IF     = "requiredIf"    //同级字段满足条件时不可为空，例如 requiredIf:"Type=disk"、requiredIf:"Type"
+	K_EQ_FIELD        = "eqField"       //必须等于同级字段，例如 eqField:"Password"
+	K_GT_FIELD        = "gtField"       //必须大于同级字段，例如 gtField:"StartTime"
+	K_ONE_OF_REQUIRED = "oneOfRequired" //与列出的同级字段中至少一个不为空，例如 oneOfRequired:"Email,Phone"
+
+	K_DETAIL_FIELD   = "field"    //错误详情中校验未通过的字段名称
+	K_DETAIL_RULE    = "rule"     //错误详情中校验未通过的规则
+	K_DETAIL_MESSAGE = "message"  //错误详情中的错误信息
+	K_RULE_REQUIRED  = "required" //非空校验未通过时，错误详情中的规则名称
 )
 
 type Rule struct {
@@ -81,11 +102,29 @@ type Rule struct {
 	Ascii   bool `json:",omitempty"` //ASCII
 	Ipv4    bool `json:",omitempty"` //ipv4
 
+	Ipv6       bool     `json:",omitempty"` //ipv6
+	Ip         bool     `json:",omitempty"` //ipv4或ipv6
+	Cidr       bool     `json:",omitempty"` //网段
+	CidrIn     []string `json:",omitempty"` //在指定的网段内
+	Email      bool     `json:",omitempty"` //邮箱地址
+	Url        bool     `json:",omitempty"` //绝对url
+	Uuid       bool     `json:",omitempty"` //uuid
+	Hostname   bool     `json:",omitempty"` //主机名
+	TimeLayout string   `json:",omitempty"` //时间格式
+	Json       bool     `json:",omitempty"` //json字符串
+
 	MinSumLen     interface{} `json:",omitempty"` //数组元素所有值总长度最小
 	MaxSumLen     interface{} `json:",omitempty"` //数组元素所有值总长度最大
 	NonRepeatable bool        `json:",omitempty"` //数组不允许元素重复
 	ItemMinLen    interface{} `json:",omitempty"` //数组中每个元素的最小长度
 	ItemMaxLen    interface{} `json:",omitempty"` //数组中每个元素的最大长度
+
+	RequiredIf    string   `json:",omitempty"` //同级字段满足条件时不可为空
+	EqField       string   `json:",omitempty"` //必须等于同级字段
+	GtField       string   `json:",omitempty"` //必须大于同级字段
+	OneOfRequired []string `json:",omitempty"` //与列出的同级字段中至少一个不为空
+
+	Customs []*CustomRule `json:",omitempty"` //自定义规则
 }
 
 // model缓存，保存所有需要验证的model
@@ -118,7 +157,7 @@ func (validator *modelValidator) initModel(modelName string, model interface{})
 	rules := make(map[string]*Rule)
 
 	// 生成model的字段验证规则，并缓存到map中
-	validator.createColsRules(modelType, rules, "")
+	validator.createColsRules(modelType, rules, "", map[reflect.Type]bool{})
 
 	// 加入全局缓存
 	defaultValidator.addModel(modelName, rules)
@@ -156,8 +195,14 @@ func (mvr *modelValidator) getPropRules(modelName string) *propRules {
 	return mvr.models[modelName]
 }
 
-// 获得model中的所有rule
-func (mvr *modelValidator) createColsRules(model reflect.Type, rules map[string]*Rule, pName string) {
+// 获得model中的所有rule，stack中为正在处理的类型，引用自身类型的字段不再递归
+func (mvr *modelValidator) createColsRules(model reflect.Type, rules map[string]*Rule, pName string, stack map[reflect.Type]bool) {
+	if stack[model] {
+		return
+	}
+	stack[model] = true
+	defer delete(stack, model)
+
 	for i := 0; i < model.NumField(); i++ {
 		field := model.Field(i)
 		fieldName := getFieldName(pName, field.Name)
@@ -169,48 +214,61 @@ func (mvr *modelValidator) createColsRules(model reflect.Type, rules map[string]
 
 		// 递归
 		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct { //对象数组
-			mvr.createColsRules(field.Type.Elem(), rules, fieldName)
+			mvr.createColsRules(field.Type.Elem(), rules, fieldName, stack)
 		} else if field.Type.Kind() == reflect.Struct { //对象
-			mvr.createColsRules(field.Type, rules, fieldName)
+			mvr.createColsRules(field.Type, rules, fieldName, stack)
 		} else if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct { //对象指针
-			mvr.createColsRules(field.Type.Elem(), rules, fieldName)
+			mvr.createColsRules(field.Type.Elem(), rules, fieldName, stack)
 		} else if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Ptr && field.Type.Elem().Elem().Kind() == reflect.Struct { //对象数组指针
-			mvr.createColsRules(field.Type.Elem().Elem(), rules, fieldName)
+			mvr.createColsRules(field.Type.Elem().Elem(), rules, fieldName, stack)
 		}
 
 		if hasRule(string(fieldTag)) == false {
 			continue
 		}
-		rule := &Rule{}
-		rule.Name = fieldName
-		rule.Type = field.Type
-		rule.setNilable(fieldTag)
-		rule.setLen(fieldTag)
-		rule.setMinLen(fieldTag)
-		rule.setMaxLen(fieldTag)
-		rule.setEqual(fieldTag)
-		rule.setBigger(fieldTag)
-		rule.setBiggerEq(fieldTag)
-		rule.setLower(fieldTag)
-		rule.setLowerEq(fieldTag)
-		rule.setStartWith(fieldTag)
-		rule.setEndWith(fieldTag)
-		rule.setReg(fieldTag)
-		rule.setInList(fieldTag)
-		rule.setUnBase64MaxLen(fieldTag)
-
-		rule.setMinSumLen(fieldTag)
-		rule.setMaxSumLen(fieldTag)
-		rule.setAbsPath(fieldTag)
-		rule.setAscii(fieldTag)
-		rule.setIpv4(fieldTag)
-		rule.setNonRepeatable(fieldTag)
-		rule.setItemMinLen(fieldTag)
-		rule.setItemMaxLen(fieldTag)
-		rules[fieldName] = rule
+		rules[fieldName] = newRule(fieldName, field)
 	}
 }
 
+// 按字段上的标签生成校验规则
+func newRule(fieldName string, field reflect.StructField) *Rule {
+	tag := field.Tag
+	rule := &Rule{}
+	rule.Name = fieldName
+	rule.Type = field.Type
+	rule.setNilable(tag)
+	rule.setLen(tag)
+	rule.setMinLen(tag)
+	rule.setMaxLen(tag)
+	rule.setEqual(tag)
+	rule.setBigger(tag)
+	rule.setBiggerEq(tag)
+	rule.setLower(tag)
+	rule.setLowerEq(tag)
+	rule.setStartWith(tag)
+	rule.setEndWith(tag)
+	rule.setReg(tag)
+	rule.setInList(tag)
+	rule.setUnBase64MaxLen(tag)
+
+	rule.setMinSumLen(tag)
+	rule.setMaxSumLen(tag)
+	rule.setAbsPath(tag)
+	rule.setAscii(tag)
+	rule.setIpv4(tag)
+	rule.setNonRepeatable(tag)
+	rule.setItemMinLen(tag)
+	rule.setItemMaxLen(tag)
+	rule.setFormats(tag)
+
+	rule.setRequiredIf(tag)
+	rule.setEqField(tag)
+	rule.setGtField(tag)
+	rule.setOneOfRequired(tag)
+	rule.setCustoms(tag)
+	return rule
+}
+
 func (rule *Rule) setNilable(tag reflect.StructTag) {
 	if hasNilable(string(tag)) {
 		rule.Nilable = true
@@ -355,20 +413,41 @@ func (rule *Rule) setItemMaxLen(tag reflect.StructTag) {
 		rule.ItemMaxLen = tagval
 	}
 }
+func (rule *Rule) setRequiredIf(tag reflect.StructTag) {
+	rule.RequiredIf = tag.Get(K_REQUIRED_IF)
+}
+func (rule *Rule) setEqField(tag reflect.StructTag) {
+	rule.EqField = tag.Get(K_EQ_FIELD)
+}
+func (rule *Rule) setGtField(tag reflect.StructTag) {
+	rule.GtField = tag.Get(K_GT_FIELD)
+}
+func (rule *Rule) setOneOfRequired(tag reflect.StructTag) {
+	tagval := tag.Get(K_ONE_OF_REQUIRED)
+	if tagval != "" {
+		rule.OneOfRequired = strings.Split(tagval, ",")
+	}
+}
 
 // 开始验证model
-func (mvr *modelValidator) beginValidate(model interface{}, rules map[string]*Rule) error {
+func (mvr *modelValidator) beginValidate(model interface{}, rules map[string]*Rule, c *collector) error {
 	modelValue := reflect.ValueOf(model)
 	modelType := reflect.TypeOf(model)
 	if modelValue.Kind() == reflect.Ptr {
 		modelValue = modelValue.Elem()
 		modelType = modelType.Elem()
 	}
-	return mvr.validate(modelValue, modelType, rules, "")
+	return mvr.validate(modelValue, modelType, rules, "", "", c)
 }
 
 // 验证model
-func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type, rules map[string]*Rule, pName string) error {
+// pName 为规则中的字段全名称，path 为错误详情中使用的json字段路径，例如 tags[0].key
+func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type, rules map[string]*Rule, pName, path string, c *collector) error {
+
+	// 引用同级字段的规则
+	if err := mvr.validateCross(model, modelType, rules, pName, path, c); err != nil {
+		return err
+	}
 
 	for i := 0; i < model.NumField(); i++ {
 
@@ -377,10 +456,12 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 
 		// 字段名称
 		fieldName := getFieldName(pName, fieldType.Name)
+		fieldPath := getFieldName(path, jsonName(fieldType))
 
 		// 如果是匿名字段，字段路径中删除匿名名称
 		if fieldType.Anonymous {
 			fieldName = pName
+			fieldPath = path
 		}
 
 		// 得到字段值
@@ -397,10 +478,11 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 				}
 				// 非空校验
 				if rule.Nilable == false {
-					return notEmptyError(fieldName)
-				} else {
-					continue
+					if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+						return err
+					}
 				}
+				continue
 
 				// 指针值不为空，取出其中的对象
 			} else if field.Elem().Kind() == reflect.Struct {
@@ -414,14 +496,18 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 			case basic.TimeStandard, *basic.TimeStandard:
 				if rule := rules[fieldName]; rule != nil && rule.Nilable == false {
 					if v := field.Interface().(basic.TimeStandard); v.IsZero() {
-						return notEmptyError(fieldName)
+						if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+							return err
+						}
 					}
 				}
 				continue
 			case basic.Day, *basic.Day:
 				if rule := rules[fieldName]; rule != nil && rule.Nilable == false {
 					if v := field.Interface().(basic.Day); v.IsZero() {
-						return notEmptyError(fieldName)
+						if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+							return err
+						}
 					}
 				}
 				continue
@@ -433,11 +519,13 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 			// 本身非空校验
 			if rule := rules[fieldName]; rule != nil {
 				if rule.Nilable == false && field.Interface() == nil {
-					return notEmptyError(fieldName)
+					if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+						return err
+					}
 				}
 			}
 			// 递归
-			if err := mvr.validate(field, field.Type(), rules, fieldName); err != nil {
+			if err := mvr.validate(field, field.Type(), rules, fieldName, fieldPath, c); err != nil {
 				return err
 			}
 			continue
@@ -456,11 +544,15 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 			if rule := rules[fieldName]; rule != nil {
 				// 本身非空校验
 				if rule.Nilable == false && field.Len() == 0 {
-					return notEmptyError(fieldName)
+					if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+						return err
+					}
 				}
 				// 对象数组其它规则校验
 				if err := mvr.validateField(rule, field); err != nil {
-					return err
+					if err := c.add(err, fieldPath); err != nil {
+						return err
+					}
 				}
 			}
 			// 递归
@@ -468,10 +560,14 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 				subField := field.Index(i)
 				subFieldType := field.Type().Elem()
 				if isSlicePrt {
+					// 数组中的空指针不校验
+					if subField.IsNil() {
+						continue
+					}
 					subField = subField.Elem()
 					subFieldType = subFieldType.Elem()
 				}
-				if err := mvr.validate(subField, subFieldType, rules, fieldName); err != nil {
+				if err := mvr.validate(subField, subFieldType, rules, fieldName, fmt.Sprintf("%s[%d]", fieldPath, i), c); err != nil {
 					return err
 				}
 			}
@@ -486,7 +582,10 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 
 		// 非空校验
 		if rule.Nilable == false && field.Interface() == nil {
-			return notEmptyError(fieldName)
+			if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+				return err
+			}
+			continue
 		}
 
 		if field.Kind() == reflect.Interface {
@@ -495,87 +594,53 @@ func (mvr *modelValidator) validate(model reflect.Value, modelType reflect.Type,
 
 		// 如果字段是普通类型
 		// 首先进行空或非空校验
-		// 如果字段可空并且value是空的，则继续循环下一个字段，如果字段不可空并且value是空的，直接返回不可空错误
+		// 如果字段可空并且value是空的，则继续循环下一个字段，如果字段不可空并且value是空的，记录不可空错误
+		empty := false
 		switch field.Interface().(type) {
 		case string:
-			if rule.Nilable && field.String() == "" {
-				continue
-			}
-			if rule.Nilable == false && field.String() == "" {
-				return notEmptyError(fieldName)
-			}
+			empty = field.String() == ""
 		case *string:
-			if rule.Nilable && field.IsNil() {
-				continue
-			}
-			if rule.Nilable == false && field.IsNil() {
-				return notEmptyError(fieldName)
+			if empty = field.IsNil(); !empty {
+				field = field.Elem()
 			}
-			field = field.Elem()
 		case int, int32, int64:
 			if rule.Nilable && field.Int() == 0 {
 				continue
 			}
 		case *int, *int32, *int64:
-			if rule.Nilable && field.IsNil() {
-				continue
-			}
-			if rule.Nilable == false && field.IsNil() {
-				return notEmptyError(fieldName)
+			if empty = field.IsNil(); !empty {
+				field = field.Elem()
 			}
-			field = field.Elem()
 		case bool:
 		case *bool:
-		case float32:
-			if rule.Nilable && field.Float() == 0 {
-				continue
-			}
-		case float64:
+		case float32, float64:
 			if rule.Nilable && field.Float() == 0 {
 				continue
 			}
-		case []string:
-			if rule.Nilable && field.Len() == 0 {
-				continue
-			}
-			if rule.Nilable == false && field.Len() == 0 {
-				return notEmptyError(fieldName)
-			}
-		case []int:
-			if rule.Nilable && field.Len() == 0 {
-				continue
-			}
-			if rule.Nilable == false && field.Len() == 0 {
-				return notEmptyError(fieldName)
-			}
-		case []bool:
-			if rule.Nilable && field.Len() == 0 {
-				continue
-			}
-			if rule.Nilable == false && field.Len() == 0 {
-				return notEmptyError(fieldName)
-			}
-		case []float32:
-			if rule.Nilable && field.Len() == 0 {
-				continue
-			}
-			if rule.Nilable == false && field.Len() == 0 {
-				return notEmptyError(fieldName)
+		case []string, []int, []bool, []float32, []float64:
+			empty = field.Len() == 0
+		default:
+			err := ruleError(K_VERF, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Validator error. Parameter %s unsupported data type", fieldName), nil))
+			if err := c.add(err, fieldPath); err != nil {
+				return err
 			}
-		case []float64:
-			if rule.Nilable && field.Len() == 0 {
+			continue
+		}
+		if empty {
+			if rule.Nilable {
 				continue
 			}
-			if rule.Nilable == false && field.Len() == 0 {
-				return notEmptyError(fieldName)
+			if err := c.add(notEmptyError(fieldName), fieldPath); err != nil {
+				return err
 			}
-		default:
-			return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Validator error. Parameter %s unsupported data type", fieldName), nil)
+			continue
 		}
 
 		// 到这里，value一定不是空的，对value进行校验
 		if err := mvr.validateField(rule, field); err != nil {
-			return err
+			if err := c.add(err, fieldPath); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -590,7 +655,7 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			real_len = tools.Length(value.String())
 		}
 		if real_len != rule.Len {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.MinLen != nil {
@@ -600,7 +665,7 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			real_len = tools.Length(value.String())
 		}
 		if real_len < rule.MinLen.(int) {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_MIN_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.MaxLen != nil {
@@ -610,7 +675,7 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			real_len = tools.Length(value.String())
 		}
 		if real_len > rule.MaxLen.(int) {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_MAX_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.Equal != nil {
@@ -621,56 +686,56 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 		if sv != rule.Equal {
 			switch value.Interface().(type) {
 			case string, *string, bool, *bool:
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, sv), nil)
+				return ruleError(K_EQUAL, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, sv), nil))
 			default:
-				return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+				return ruleError(K_EQUAL, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 			}
 		}
 	}
 	if rule.Bigger != nil {
 		v, err := tools.CompareNumber(value.Interface(), rule.Bigger.(string), 1)
 		if err != nil {
-			return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+			return ruleError(K_BIGGER, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 		}
 		if v == false {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_BIGGER, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.BiggerEq != nil {
 		v, err := tools.CompareNumber(value.Interface(), rule.BiggerEq.(string), 2)
 		if err != nil {
-			return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+			return ruleError(K_BIGGER_EQ, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 		}
 		if v == false {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_BIGGER_EQ, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.Lower != nil {
 		v, err := tools.CompareNumber(value.Interface(), rule.Lower.(string), 3)
 		if err != nil {
-			return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+			return ruleError(K_LOWER, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 		}
 		if v == false {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_LOWER, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.LowerEq != nil {
 		v, err := tools.CompareNumber(value.Interface(), rule.LowerEq.(string), 4)
 		if err != nil {
-			return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+			return ruleError(K_LOWER_EQ, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 		}
 		if v == false {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_LOWER_EQ, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.StartWith != "" {
 		if ok := tools.StartWith(value.String(), rule.StartWith); ok == false {
-			return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, value.String()), nil)
+			return ruleError(K_START_WITH, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, value.String()), nil))
 		}
 	}
 	if rule.EndWith != "" {
 		if ok := tools.EndWith(value.String(), rule.EndWith); ok == false {
-			return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, value.String()), nil)
+			return ruleError(K_END_WITH, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, value.String()), nil))
 		}
 	}
 	if rule.Reg != "" {
@@ -679,12 +744,12 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			for i := 0; i < value.Len(); i++ {
 				val := value.Index(i).String()
 				if ok := tools.MatchReg(val, rule.Reg); ok == false {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, val), nil)
+					return ruleError(K_REG, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, val), nil))
 				}
 			}
 		default:
 			if ok := tools.MatchReg(value.String(), rule.Reg); ok == false {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, value.String()), nil)
+				return ruleError(K_REG, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", rule.Name, value.String()), nil))
 			}
 		}
 	}
@@ -694,45 +759,45 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 		case []string:
 			for i := 0; i < value.Len(); i++ {
 				if ok := tools.ContainsString2(ms, value.Index(i).String(), rule.InList2); ok == false {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, value.Index(i).String()), nil)
+					return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, value.Index(i).String()), nil))
 				}
 			}
 		case string:
 			if ok := tools.ContainsString2(ms, value.String(), rule.InList2); ok == false {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		case []int:
 			for i := 0; i < value.Len(); i++ {
 				if ok := tools.ContainsString2(ms, tools.ToString(value.Index(i).Int()), rule.InList2); ok == false {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value.Index(i).Int())), nil)
+					return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value.Index(i).Int())), nil))
 				}
 			}
 		case int:
 			if ok := tools.ContainsString2(ms, tools.ToString(value.Int()), rule.InList2); ok == false {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		case []float32:
 			for i := 0; i < value.Len(); i++ {
 				if ok := tools.ContainsString2(ms, tools.ToString(value.Index(i).Float()), rule.InList2); ok == false {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value.Index(i).Float())), nil)
+					return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value.Index(i).Float())), nil))
 				}
 			}
 		case float32:
 			if ok := tools.ContainsString2(ms, tools.ToString(value.Float()), rule.InList2); ok == false {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		case []float64:
 			for i := 0; i < value.Len(); i++ {
 				if ok := tools.ContainsString2(ms, tools.ToString(value.Index(i).Float()), rule.InList2); ok == false {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value.Index(i).Float())), nil)
+					return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value.Index(i).Float())), nil))
 				}
 			}
 		case float64:
 			if ok := tools.ContainsString2(ms, tools.ToString(value.Float()), rule.InList2); ok == false {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		default:
-			return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s' unsupported data type", rule.Name, tools.ToString(value)), nil)
+			return ruleError(K_IN_LIST, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s' unsupported data type", rule.Name, tools.ToString(value)), nil))
 		}
 	}
 	if rule.unBase64MaxLen != nil {
@@ -740,19 +805,19 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 		case []string:
 			for i := 0; i < value.Len(); i++ {
 				if decode, err := base64.StdEncoding.DecodeString(value.Index(i).String()); err != nil {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("%s[%d] incorrect base64 encoding.", rule.Name, i), nil)
+					return ruleError(K_UNBASE64_MAX_LEN, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("%s[%d] incorrect base64 encoding.", rule.Name, i), nil))
 				} else {
 					if real_len := tools.Length(string(decode)); real_len > rule.unBase64MaxLen.(int) {
-						return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s[%d] out of range", rule.Name, i), nil)
+						return ruleError(K_UNBASE64_MAX_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s[%d] out of range", rule.Name, i), nil))
 					}
 				}
 			}
 		default:
 			if decode, err := base64.StdEncoding.DecodeString(value.String()); err != nil {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("%s incorrect base64 encoding.", rule.Name), nil)
+				return ruleError(K_UNBASE64_MAX_LEN, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("%s incorrect base64 encoding.", rule.Name), nil))
 			} else {
 				if real_len := tools.Length(string(decode)); real_len > rule.unBase64MaxLen.(int) {
-					return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+					return ruleError(K_UNBASE64_MAX_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 				}
 			}
 		}
@@ -770,7 +835,7 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 		}
 
 		if lenSum < rule.MinSumLen.(int) {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_MIN_SUM_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.MaxSumLen != nil {
@@ -785,7 +850,7 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 		}
 
 		if lenSum > rule.MaxSumLen.(int) {
-			return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+			return ruleError(K_MAX_SUM_LEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 		}
 	}
 	if rule.AbsPath {
@@ -794,13 +859,13 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			for i := 0; i < value.Len(); i++ {
 				val := value.Index(i).String()
 				if !(len(val) > 0 && val[0] == '/') {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+					return ruleError(K_ABS_PATH, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 				}
 			}
 		default:
 			val := value.String()
 			if !(len(val) > 0 && val[0] == '/') {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_ABS_PATH, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		}
 	}
@@ -811,12 +876,12 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			for i := 0; i < value.Len(); i++ {
 				val := value.Index(i).String()
 				if !pattern.Match([]byte(val)) {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+					return ruleError(K_ASCII, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 				}
 			}
 		default:
 			if !pattern.Match([]byte(value.String())) {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_ASCII, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		}
 	}
@@ -827,12 +892,12 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			for i := 0; i < value.Len(); i++ {
 				val := value.Index(i).String()
 				if !pattern.Match([]byte(val)) {
-					return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+					return ruleError(K_IPV4, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 				}
 			}
 		default:
 			if !pattern.Match([]byte(value.String())) {
-				return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil)
+				return ruleError(K_IPV4, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Invalid %s '%s'", rule.Name, tools.ToString(value)), nil))
 			}
 		}
 	}
@@ -842,8 +907,8 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			if _, in := repeatFlags[element]; !in {
 				repeatFlags[element] = struct{}{}
 			} else {
-				return e.NewApiError(e.INVALID_ARGUMENT,
-					fmt.Sprintf("Duplicated element '%s' in %s.", element, rule.Name), nil)
+				return ruleError(K_NON_REPEATABLE, e.NewApiError(e.INVALID_ARGUMENT,
+					fmt.Sprintf("Duplicated element '%s' in %s.", element, rule.Name), nil))
 			}
 			return nil
 		}
@@ -869,19 +934,19 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			for i := 0; i < value.Len(); i++ {
 				v, err := tools.CompareNumber(tools.Length(value.Index(i).String()), rule.ItemMinLen.(string), 3)
 				if err != nil {
-					return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+					return ruleError(K_ITEM_MINLEN, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 				}
 				if v {
-					return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s[%d] out of range", rule.Name, i), nil)
+					return ruleError(K_ITEM_MINLEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s[%d] out of range", rule.Name, i), nil))
 				}
 			}
 		default:
 			v, err := tools.CompareNumber(tools.Length(value.String()), rule.ItemMinLen.(string), 3)
 			if err != nil {
-				return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+				return ruleError(K_ITEM_MINLEN, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 			}
 			if v == false {
-				return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+				return ruleError(K_ITEM_MINLEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 			}
 		}
 	}
@@ -892,29 +957,55 @@ func (mvr *modelValidator) validateField(rule *Rule, value reflect.Value) error
 			for i := 0; i < value.Len(); i++ {
 				v, err := tools.CompareNumber(tools.Length(value.Index(i).String()), rule.ItemMaxLen.(string), 1)
 				if err != nil {
-					return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+					return ruleError(K_ITEM_MAXLEN, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 				}
 				if v {
-					return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s[%d] out of range", rule.Name, i), nil)
+					return ruleError(K_ITEM_MAXLEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s[%d] out of range", rule.Name, i), nil))
 				}
 			}
 		default:
 			v, err := tools.CompareNumber(tools.Length(value.String()), rule.ItemMaxLen.(string), 1)
 			if err != nil {
-				return e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil)
+				return ruleError(K_ITEM_MAXLEN, e.NewApiError(e.INVALID_ARGUMENT, err.Error(), nil))
 			}
 			if v == false {
-				return e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil)
+				return ruleError(K_ITEM_MAXLEN, e.NewApiError(e.OUT_OF_RANGE, fmt.Sprintf("%s out of range", rule.Name), nil))
 			}
 		}
 	}
 
+	// 格式校验
+	if err := checkFormats(rule, value); err != nil {
+		return err
+	}
+
+	// 自定义规则
+	for _, custom := range rule.Customs {
+		if err := custom.check(rule, value); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // 非空错误
 func notEmptyError(fieldName string) error {
-	return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Parameter %s missing", fieldName), nil)
+	return ruleError(K_RULE_REQUIRED, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Parameter %s missing", fieldName), nil))
+}
+
+// 在错误详情中记录校验未通过的规则
+func ruleError(key string, err e.ApiError) e.ApiError {
+	err.AddDetail(K_DETAIL_RULE, key)
+	return err
+}
+
+// 在错误详情中记录校验未通过的字段，使用json字段路径，与json解析错误中的字段一致
+func fieldError(err error, path string) error {
+	if apiErr, ok := err.(e.ApiError); ok {
+		apiErr.AddDetail(K_DETAIL_FIELD, path)
+	}
+	return err
 }
 
 // 拼接父子名称
@@ -956,8 +1047,13 @@ func hasNonRepeatable(tag string) bool {
 	return NON_REPEATABLE.Match([]byte(tag))
 }
 
-// 对外接口，验证一个Model数据是否合法
+// 对外接口，验证一个Model数据是否合法，遇到第一个未通过校验的字段即返回
 func Validate(model interface{}) error {
+	return defaultValidator.beginValidate(model, getRules(model), &collector{})
+}
+
+// 获得model对应的所有字段的rule
+func getRules(model interface{}) map[string]*Rule {
 
 	// model名称
 	modelName := basic.GetClassName(model)
@@ -965,16 +1061,9 @@ func Validate(model interface{}) error {
 	// 获得model对应的所有字段的rule
 	propRules := defaultValidator.getPropRules(modelName)
 
-	// rules
-	var rules map[string]*Rule
-
 	// 如果缓存中没有这个model的rule，则开始初始化并加入缓存
 	if propRules == nil {
-		rules = defaultValidator.initModel(modelName, model)
-	} else {
-		rules = propRules.rules
+		return defaultValidator.initModel(modelName, model)
 	}
-
-	// 开始校验
-	return defaultValidator.beginValidate(model, rules)
+	return propRules.rules
 }
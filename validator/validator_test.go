@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/stretchr/testify/assert"
+)
+
+type tag struct {
+	Key   string `json:"key" maxLen:"3" verf:""`
+	Value string `json:"value" verf:"" nilable:""`
+}
+
+type createRequest struct {
+	Name  string  `json:"name" maxLen:"4" verf:""`
+	Count int     `json:"count" biggerEq:"1" lowerEq:"10" verf:""`
+	Mode  *string `inList:"a,b" verf:""`
+	Tags  []tag   `json:"tags" verf:"" nilable:""`
+	Owner *tag    `json:"owner" verf:"" nilable:""`
+}
+
+func TestValidateAll(t *testing.T) {
+	mode := "c"
+	req := &createRequest{
+		Name:  "abcdef",
+		Count: 11,
+		Mode:  &mode,
+		Tags:  []tag{{Key: "a"}, {Key: "abcd"}, {}},
+	}
+
+	// 默认只返回第一个错误
+	err := Validate(req)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "name", err.(e.ApiError).GetDetails()[0][K_DETAIL_FIELD])
+	}
+
+	err = ValidateAll(req)
+	if assert.NotNil(t, err) {
+		apiErr := err.(e.ApiError)
+		assert.Equal(t, "INVALID_ARGUMENT", apiErr.GetType())
+		assert.Equal(t, []map[string]string{
+			{K_DETAIL_FIELD: "name", K_DETAIL_RULE: K_MAX_LEN, K_DETAIL_MESSAGE: "Name out of range"},
+			{K_DETAIL_FIELD: "count", K_DETAIL_RULE: K_LOWER_EQ, K_DETAIL_MESSAGE: "Count out of range"},
+			{K_DETAIL_FIELD: "Mode", K_DETAIL_RULE: K_IN_LIST, K_DETAIL_MESSAGE: "Invalid Mode 'c'"},
+			{K_DETAIL_FIELD: "tags[1].key", K_DETAIL_RULE: K_MAX_LEN, K_DETAIL_MESSAGE: "Tags.Key out of range"},
+			{K_DETAIL_FIELD: "tags[2].key", K_DETAIL_RULE: K_RULE_REQUIRED, K_DETAIL_MESSAGE: "Parameter Tags.Key missing"},
+		}, apiErr.GetDetails())
+	}
+
+	mode = "a"
+	assert.Nil(t, ValidateAll(&createRequest{Name: "abc", Count: 1, Mode: &mode, Owner: &tag{Key: "k"}}))
+}
+
+type crossRequest struct {
+	Type      string    `verf:""`
+	Disk      *int      `requiredIf:"Type=disk" verf:"" nilable:""`
+	Password  string    `verf:""`
+	Confirm   string    `eqField:"Password" verf:""`
+	StartTime basic.Day `verf:"" nilable:""`
+	EndTime   basic.Day `gtField:"StartTime" verf:"" nilable:""`
+	Email     string    `oneOfRequired:"Phone" verf:"" nilable:""`
+	Phone     string    `verf:"" nilable:""`
+	Count     int       `even:"" verf:"" nilable:""`
+}
+
+func TestValidate_Cross(t *testing.T) {
+	RegisterRule("even", func(value reflect.Value, param string) error {
+		if value.Int()%2 != 0 {
+			return fmt.Errorf("%d is not even", value.Int())
+		}
+		return nil
+	})
+
+	start, _ := basic.NewDay("2024-01-02")
+	end, _ := basic.NewDay("2024-01-01")
+	err := ValidateAll(&crossRequest{Type: "disk", Password: "a", Confirm: "b", StartTime: start, EndTime: end, Count: 3})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, []map[string]string{
+			{K_DETAIL_FIELD: "Disk", K_DETAIL_RULE: K_REQUIRED_IF, K_DETAIL_MESSAGE: "Parameter Disk missing when Type is 'disk'"},
+			{K_DETAIL_FIELD: "Confirm", K_DETAIL_RULE: K_EQ_FIELD, K_DETAIL_MESSAGE: "Confirm must be equal to Password"},
+			{K_DETAIL_FIELD: "EndTime", K_DETAIL_RULE: K_GT_FIELD, K_DETAIL_MESSAGE: "EndTime must be greater than StartTime"},
+			{K_DETAIL_FIELD: "Email", K_DETAIL_RULE: K_ONE_OF_REQUIRED, K_DETAIL_MESSAGE: "One of Email, Phone is required"},
+			{K_DETAIL_FIELD: "Count", K_DETAIL_RULE: "even", K_DETAIL_MESSAGE: "Invalid Count. 3 is not even"},
+		}, err.(e.ApiError).GetDetails())
+	}
+
+	disk := 10
+	assert.Nil(t, ValidateAll(&crossRequest{Type: "disk", Disk: &disk, Password: "a", Confirm: "a", StartTime: end, EndTime: start, Phone: "1", Count: 2}))
+	assert.Nil(t, Validate(&crossRequest{Type: "cloud", Password: "a", Confirm: "a", Email: "a@b.c"}))
+}
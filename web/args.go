@@ -15,7 +15,7 @@ import (
 )
 
 // 转换http请求的参数，封装为目标请求参数
-func (h *commonHandler) createArg(r *http.Request, arg reflect.Value, ctx *ReqContext) error {
+func (h *commonHandler) createArg(w http.ResponseWriter, r *http.Request, arg reflect.Value, ctx *ReqContext) error {
 
 	// GET参数
 	if r.Method == HTTP_GET || r.Method == HTTP_DELETE {
@@ -34,13 +34,17 @@ func (h *commonHandler) createArg(r *http.Request, arg reflect.Value, ctx *ReqCo
 		// POST参数
 	} else if r.Method == HTTP_POST || r.Method == HTTP_PUT || r.Method == HTTP_PATCH {
 
-		// 首先处理body流，json body
-		if err := h.createPostJsonArg(r, arg, ctx); err != nil {
+		// 首先处理body流，表单中的参数合并到url参数中，json body直接解析
+		if isFormRequest(r) {
+			if err := h.parseForm(w, r, ctx); err != nil {
+				return err
+			}
+		} else if err := h.createPostJsonArg(r, arg, ctx); err != nil {
 			return err
 		}
 
 		// 如果post请求的url上有parameters，还需要处理一次url上的参数
-		if len(ctx.params) > 0 {
+		if len(ctx.params) > 0 || len(ctx.files) > 0 {
 			stru := arg
 			if stru.Kind() == reflect.Ptr {
 				stru = stru.Elem()
@@ -52,6 +56,15 @@ func (h *commonHandler) createArg(r *http.Request, arg reflect.Value, ctx *ReqCo
 	} else {
 		return e.NewApiError(e.UNAVAILABLE, fmt.Sprintf("Http method %s is not supported.", r.Method), nil)
 	}
+
+	// 请求头和cookie中的参数
+	stru := arg
+	if stru.Kind() == reflect.Ptr {
+		stru = stru.Elem()
+	}
+	if _, err := h.createHeaderArg(r, stru); err != nil {
+		return err
+	}
 	ctx.SetRequest(arg.Interface())
 	return nil
 }
@@ -79,6 +92,14 @@ func (h *commonHandler) createGetArg(arg reflect.Value, ctx *ReqContext, pName s
 			anonymousName = anonymousName + afType.Name + "."
 		}
 
+		// 上传的文件
+		if f.Type() == fileHeaderType || f.Type() == fileHeadersType {
+			if h.setFile(afType.Name, pName, anonymousName, ctx, &f) {
+				obj_changed = true
+			}
+			continue
+		}
+
 		// 如果字段类型是指针，取其实际的值，实际的值肯定是nil，因为还没有初始化
 		real_field := f
 		if real_field.Kind() == reflect.Ptr {
@@ -310,7 +331,18 @@ func (h *commonHandler) createGetArg(arg reflect.Value, ctx *ReqContext, pName s
 				}
 			case "map[string]interface {}":
 			default:
-				return obj_changed, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Parse data error. Field %s data type is not supported", fieldName), nil)
+				// 其它数字类型，按字段类型转换
+				if !isNumberType(f.Type()) {
+					return obj_changed, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Parse data error. Field %s data type is not supported", fieldName), nil)
+				}
+				val := strings.TrimSpace(ctx.GetParamValue(fieldName))
+				if val == "" {
+					continue
+				}
+				if err := setStringValue(f, val); err != nil {
+					return obj_changed, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", fieldName, val), nil)
+				}
+				obj_changed = true
 			}
 		}
 	}
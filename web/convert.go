@@ -0,0 +1,95 @@
+package web
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/tools"
+)
+
+// 将字符串转换为字段的类型并赋值，字段为指针时创建新的值
+// 支持字符串、整数、浮点数、布尔、basic.TimeStandard、basic.Day，数组按","分隔
+func setStringValue(field reflect.Value, val string) error {
+	if field.Kind() == reflect.Ptr {
+		v := reflect.New(field.Type().Elem())
+		if err := setStringValue(v.Elem(), val); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+
+	switch field.Type() {
+	case timeType:
+		t, err := basic.NewTimeStandard(val)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case dayType:
+		d, err := basic.NewDay(val)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(val, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	case reflect.Bool:
+		v, err := tools.ToBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Slice:
+		items := strings.Split(val, ",")
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setStringValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return fmt.Errorf("data type %s is not supported", field.Type())
+	}
+	return nil
+}
+
+// 是否是数字类型或数字类型的指针
+func isNumberType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
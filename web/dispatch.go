@@ -3,6 +3,7 @@ package web
 // 用途：参数封装与请求下发
 
 import (
+	"context"
 	"fmt"
 	"github.com/Zoxu0928/task-common/api/restful"
 	"github.com/Zoxu0928/task-common/basic"
@@ -11,11 +12,11 @@ import (
 	"github.com/Zoxu0928/task-common/interceptor"
 	"github.com/Zoxu0928/task-common/logger"
 	"github.com/Zoxu0928/task-common/tools"
-	"github.com/Zoxu0928/task-common/tools/json/ffjson"
 
 	"net/http"
 	"net/url"
 	"reflect"
+	"time"
 )
 
 // 入口
@@ -27,8 +28,19 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 	// ------------------------------------------------------------------------
 	// 第一步：接收到请求后，实始化 Context，此Context在请求执行到Controller之前，会一直传递
 
+	// 客户端断开连接或超过WriteTimeout时取消请求的Context
+	var reqCtx context.Context
+	var cancel context.CancelFunc
+	if h.web.conf.WriteTimeout.Duration > 0 {
+		reqCtx, cancel = context.WithTimeout(r.Context(), h.web.conf.WriteTimeout.Duration)
+	} else {
+		reqCtx, cancel = context.WithCancel(r.Context())
+	}
+	defer cancel()
+
 	// 为当前请求初始化一个Request Context
 	ctx := &ReqContext{
+		ctx:       reqCtx,
 		attr:      map[string]interface{}{},
 		requestId: tools.GetGuid(),
 		method:    r.Method,
@@ -61,7 +73,8 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 		if err != nil {
 			h.handlerError(w, e.NewApiError(e.INTERNAL, "Internal server error", err), ctx, true)
 		}
-		restAction = restful.Match(r.Method, path)
+		var routeErr e.ApiError
+		restAction, routeErr = restful.Route(r.Method, path)
 
 		// 提取restful接口描述信息
 		if restAction != nil {
@@ -71,7 +84,13 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 			ctx.version = restAction.GetVersion()
 
 		} else {
-			h.handlerError(w, e.NewApiError(e.NOT_FOUND, "No such api.", nil), ctx, true)
+			// 路径存在但不支持此请求方式，响应头中返回支持的请求方式
+			for _, detail := range routeErr.GetDetails() {
+				if allow, ok := detail[restful.K_DETAIL_ALLOW]; ok {
+					w.Header().Set("Allow", allow)
+				}
+			}
+			h.handlerError(w, routeErr, ctx, true)
 			return
 		}
 
@@ -122,16 +141,18 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 	// ------------------------------------------------------------------------
 	// 第四步：为该Controller封装入参
 
-	// 封装入参
-	args := make([]reflect.Value, 1)
+	// 封装入参，Controller声明的 context.Context 入参在调用时传入
+	args := make([]reflect.Value, 0, 1)
 	if len(method.GetArgs()) > 0 {
 		firstArg := method.GetArgs()[0]
 		if firstArg.Kind() == reflect.Ptr {
-			args[0] = reflect.New(firstArg.Elem()) // 参数是指针
+			args = append(args, reflect.New(firstArg.Elem())) // 参数是指针
 		} else if firstArg.Kind() == reflect.Struct {
-			args[0] = reflect.New(firstArg) // 参数不是指针
+			args = append(args, reflect.New(firstArg)) // 参数不是指针
 		}
-		if err := h.createArg(r, args[0], ctx); err != nil {
+	}
+	if len(args) > 0 {
+		if err := h.createArg(w, r, args[0], ctx); err != nil {
 			h.handlerError(w, err, ctx, true)
 			return
 		}
@@ -140,10 +161,10 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// 如果是restful请求，一些参数在url和header中，需要补充到request参数里面
-	if is_restful_request {
-		params, _ := ffjson.Marshal(restAction.GetParams())
-		if rErr := ffjson.Unmarshal(params, args[0].Interface()); rErr != nil {
-			logger.Error("%s unmarshal to request failed. %s", ctx.requestId, rErr)
+	if is_restful_request && len(args) > 0 {
+		if err := h.createPathArg(args[0], restAction.GetParams()); err != nil {
+			h.handlerError(w, err, ctx, true)
+			return
 		}
 	}
 
@@ -156,10 +177,10 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// 如果是restful请求，一些参数在url和header中，需要补充到request参数里面
-	if is_restful_request {
-		params, _ := ffjson.Marshal(restAction.GetParams())
-		if rErr := ffjson.Unmarshal(params, args[0].Interface()); rErr != nil {
-			logger.Error("%s unmarshal to request failed. %s", ctx.requestId, rErr)
+	if is_restful_request && len(args) > 0 {
+		if err := h.createPathArg(args[0], restAction.GetParams()); err != nil {
+			h.handlerError(w, err, ctx, true)
+			return
 		}
 	}
 
@@ -169,8 +190,9 @@ func (h *commonHandler) dispatchHandler(w http.ResponseWriter, r *http.Request)
 	// 开始执行链条中的拦截器，并最终调用目标Controller，再反向执行链条中的拦截器
 
 	// 最终调用目标函数
+	timeout := h.timeoutOf(method, restAction)
 	doFinal := func() error {
-		return h.callTarget(method, args, ctx)
+		return h.callTarget(method, args, ctx, timeout)
 	}
 
 	// 如果当前服务绑定了拦截器，则只执行当前绑定的。如果没有绑定，执行全局的
@@ -211,9 +233,17 @@ func (h *commonHandler) hasPermission(ctx *ReqContext) bool {
 	return true
 }
 
-// 调用目标函数
-func (h *commonHandler) callTarget(method *basic.Method, args []reflect.Value, ctx *ReqContext) error {
-	outs := method.Invoke(args)
+// 调用目标函数，timeout大于0时在超时时间内执行
+func (h *commonHandler) callTarget(method *basic.Method, args []reflect.Value, ctx *ReqContext, timeout time.Duration) error {
+	var outs []reflect.Value
+	if timeout > 0 {
+		var err error
+		if outs, err = h.invokeTimeout(method, args, ctx, timeout); err != nil {
+			return err
+		}
+	} else {
+		outs = method.InvokeContext(ctx, args)
+	}
 	outTypes := method.GetReturn()
 	for i, tp := range outTypes {
 		if tp.AssignableTo(apiErrorType) {
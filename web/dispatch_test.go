@@ -0,0 +1,401 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api"
+	"github.com/Zoxu0928/task-common/api/restful"
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/controller"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/interceptor"
+	"github.com/Zoxu0928/task-common/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+type echoRequest struct {
+	api.Request
+	Name string
+}
+
+type echoResponse struct {
+	Name      string
+	RequestId string
+	TenantId  string
+	Deadline  bool
+}
+
+func Echo(ctx context.Context, req *echoRequest) (*echoResponse, error) {
+	req.SetTenantId("tenant-1")
+	_, ok := ctx.Deadline()
+	return &echoResponse{
+		Name:      req.Name,
+		RequestId: api.RequestIdFrom(ctx),
+		TenantId:  api.TenantIdFrom(ctx),
+		Deadline:  ok,
+	}, nil
+}
+
+func TestDispatch_Context(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Echo)
+	web := Load(&WebConf{}).BindController(c).BindInterceptor(interceptor.NewInterceptpr()).SetSuperRequest(new(api.Request))
+	handler := &commonHandler{web: web}
+
+	// controller的版本取自包路径的上一级
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=Echo&Version=task-common&Name=abc", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		RequestId string `json:"requestId"`
+		Result    echoResponse
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "abc", resp.Result.Name)
+	assert.Equal(t, resp.RequestId, resp.Result.RequestId)
+	assert.Equal(t, "tenant-1", resp.Result.TenantId)
+	assert.True(t, resp.Result.Deadline)
+}
+
+type recordCut struct {
+	err error
+}
+
+func (c *recordCut) BeforeFunc(ctx interceptor.Context) error { return nil }
+func (c *recordCut) AfterFunc(ctx interceptor.Context) error  { return nil }
+func (c *recordCut) OnError(ctx interceptor.Context, err error) {
+	c.err = err
+}
+
+func Slow(ctx context.Context, req *echoRequest) (*echoResponse, error) {
+	<-ctx.Done()
+	return &echoResponse{Name: req.Name}, nil
+}
+
+func TestDispatch_Timeout(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Slow)
+	cut := &recordCut{}
+	i := interceptor.NewInterceptpr()
+	pointCut := interceptor.NewPointCut("record", []string{"*"}, nil, false)
+	pointCut.CutFunc = cut
+	i.Add(pointCut)
+	web := Load(&WebConf{}).BindController(c).BindInterceptor(i).
+		SetTimeout(basic.NewMethod(nil, Slow).GetFullName(), 50*time.Millisecond)
+	handler := &commonHandler{web: web}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=Slow&Version=task-common&Name=abc", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	var resp errorResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "DEADLINE_EXCEEDED", resp.Error.Status)
+	if assert.NotNil(t, cut.err) {
+		assert.Equal(t, "DEADLINE_EXCEEDED", cut.err.(e.ApiError).GetType())
+	}
+}
+
+func Boom(ctx context.Context, req *echoRequest) (*echoResponse, error) {
+	panic("boom " + req.Name)
+}
+
+func TestDispatch_TimeoutPanic(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Boom)
+	web := Load(&WebConf{}).BindController(c).
+		SetTimeout(basic.NewMethod(nil, Boom).GetFullName(), time.Second)
+	handler := &commonHandler{web: web}
+
+	// 目标函数的panic交给统一的错误处理
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=Boom&Version=task-common&Name=abc", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var resp errorResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "INTERNAL", resp.Error.Status)
+}
+
+var latePanicked = make(chan struct{})
+
+func LatePanic(ctx context.Context, req *echoRequest) (*echoResponse, error) {
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond)
+	defer close(latePanicked)
+	panic("late " + req.Name)
+}
+
+func TestDispatch_TimeoutLatePanic(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(LatePanic)
+	web := Load(&WebConf{}).BindController(c).
+		SetTimeout(basic.NewMethod(nil, LatePanic).GetFullName(), 20*time.Millisecond)
+	handler := &commonHandler{web: web}
+
+	// 超时返回之后发生的panic在协程中记录，不影响已返回的响应
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=LatePanic&Version=task-common&Name=abc", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	select {
+	case <-latePanicked:
+	case <-time.After(time.Second):
+		t.Fatal("controller did not panic")
+	}
+}
+
+type uploadRequest struct {
+	api.Request
+	Name   string
+	Tags   []string
+	Avatar *multipart.FileHeader
+	Files  []*multipart.FileHeader
+}
+
+func Upload(req *uploadRequest) (*echoResponse, error) {
+	names := append([]string{req.Name}, req.Tags...)
+	if req.Avatar != nil {
+		names = append(names, req.Avatar.Filename)
+	}
+	for _, f := range req.Files {
+		names = append(names, f.Filename)
+	}
+	return &echoResponse{Name: strings.Join(names, ",")}, nil
+}
+
+func TestDispatch_Form(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Upload)
+	web := Load(&WebConf{MaxFormSize: 1024}).BindController(c).BindInterceptor(interceptor.NewInterceptpr())
+	handler := &commonHandler{web: web}
+
+	message := ""
+	call := func(body io.Reader, contentType string) (int, string) {
+		r := httptest.NewRequest(http.MethodPost, "/?Action=Upload&Version=task-common", body)
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		var resp struct {
+			Result echoResponse
+			Error  struct{ Message string }
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		message = resp.Error.Message
+		return w.Code, resp.Result.Name
+	}
+
+	// x-www-form-urlencoded
+	form := url.Values{"Name": {"abc"}, "Tags.1": {"a"}, "Tags.2": {"b"}}
+	code, name := call(strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "abc,a,b", name)
+
+	// multipart/form-data
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("name", "abc")
+	fw, _ := mw.CreateFormFile("Avatar", "avatar.png")
+	fw.Write([]byte("png"))
+	fw, _ = mw.CreateFormFile("Files", "1.txt")
+	fw.Write([]byte("1"))
+	fw, _ = mw.CreateFormFile("Files", "2.txt")
+	fw.Write([]byte("2"))
+	mw.Close()
+	code, name = call(buf, mw.FormDataContentType())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "abc,avatar.png,1.txt,2.txt", name)
+
+	// 超过大小限制
+	form = url.Values{"Name": {strings.Repeat("a", 2048)}}
+	code, _ = call(strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Request body too large. Limit is 1024 bytes.", message)
+
+	buf.Reset()
+	mw = multipart.NewWriter(buf)
+	fw, _ = mw.CreateFormFile("Avatar", "avatar.png")
+	fw.Write(bytes.Repeat([]byte("a"), 2048))
+	mw.Close()
+	code, _ = call(buf, mw.FormDataContentType())
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Request body too large. Limit is 1024 bytes.", message)
+
+	code, _ = call(strings.NewReader("%zz"), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid form body.", message)
+}
+
+type headerRequest struct {
+	api.Request
+	Page    *int   `header:"X-Page"`
+	Session string `cookie:"session_id"`
+}
+
+func Header(req *headerRequest) (*echoResponse, error) {
+	return &echoResponse{Name: fmt.Sprintf("%s,%s,%d,%s", req.Account, req.Role, *req.Page, req.Session)}, nil
+}
+
+func TestDispatch_Header(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Header)
+	web := Load(&WebConf{}).BindController(c).BindInterceptor(interceptor.NewInterceptpr())
+	handler := &commonHandler{web: web}
+
+	r := httptest.NewRequest(http.MethodPost, "/?Action=Header&Version=task-common", strings.NewReader(`{"Account":"body"}`))
+	r.Header.Set("X-Jcloud-Pin", "pin")
+	r.Header.Set("X-Jcloud-Role", "admin")
+	r.Header.Set("X-Page", "3")
+	r.AddCookie(&http.Cookie{Name: "session_id", Value: "s1"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Result echoResponse
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "pin,admin,3,s1", resp.Result.Name)
+
+	// 类型转换失败
+	r = httptest.NewRequest(http.MethodGet, "/?Action=Header&Version=task-common", nil)
+	r.Header.Set("X-Page", "abc")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// 不区分版本的controller，用于restful请求
+type anyVersionController struct {
+	controller.Controller
+	method *basic.Method
+}
+
+func (c *anyVersionController) GetController(methodName, version string) *basic.Method {
+	return c.method
+}
+
+type pathRequest struct {
+	api.Request
+	Id     int64
+	Enable bool
+	Ratio  *float64
+	Day    basic.Day
+}
+
+func Path(req *pathRequest) (*echoResponse, error) {
+	return &echoResponse{Name: fmt.Sprintf("%d,%t,%.1f,%s", req.Id, req.Enable, *req.Ratio, req.Day.String())}, nil
+}
+
+func TestDispatch_PathParams(t *testing.T) {
+	restful.RegisterApi(restful.NewRestful().ServiceName("web").Method(restful.GET).Uri("/paths/{id}/{enable}/{ratio}/{day}").SupportVersion("v1").Action("Path"))
+	c := &anyVersionController{Controller: controller.NewController(false), method: basic.NewMethod(nil, Path)}
+	web := Load(&WebConf{}).BindController(c).BindInterceptor(interceptor.NewInterceptpr())
+	handler := &commonHandler{web: web}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/paths/12/true/0.5/2024-01-02", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Result echoResponse
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "12,true,0.5,2024-01-02", resp.Result.Name)
+
+	// 类型转换失败时返回参数名称
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/paths/abc/true/0.5/2024-01-02", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errResp errorResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "INVALID_ARGUMENT", errResp.Error.Status)
+	assert.Equal(t, []map[string]string{{restful.K_DETAIL_FIELD: "id"}}, errResp.Error.Details)
+}
+
+type validateRequest struct {
+	api.Request
+	Name  string `json:"name" maxLen:"3" verf:""`
+	Count int    `json:"count" biggerEq:"1" verf:""`
+}
+
+func Validate(req *validateRequest) (*echoResponse, error) {
+	return &echoResponse{Name: req.Name}, nil
+}
+
+func TestDispatch_Validate(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Validate)
+	i := interceptor.NewInterceptpr()
+	i.Add(validator.NewPointCut([]string{"*"}, nil))
+	web := Load(&WebConf{}).BindController(c).BindInterceptor(i)
+	handler := &commonHandler{web: web}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=Validate&Version=task-common&Name=abcd&Count=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp errorResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "INVALID_ARGUMENT", resp.Error.Status)
+	assert.Equal(t, []map[string]string{
+		{validator.K_DETAIL_FIELD: "name", validator.K_DETAIL_RULE: validator.K_MAX_LEN, validator.K_DETAIL_MESSAGE: "Name out of range"},
+		{validator.K_DETAIL_FIELD: "count", validator.K_DETAIL_RULE: validator.K_BIGGER_EQ, validator.K_DETAIL_MESSAGE: "Count out of range"},
+	}, resp.Error.Details)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=Validate&Version=task-common&Name=abc&Count=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWriteSchema(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Validate)
+	web := Load(&WebConf{SchemaPath: "/schema"}).BindController(c)
+	handler := &commonHandler{web: web}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/schema?Action=Validate&Version=task-common", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var schema validator.Schema
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &schema))
+	assert.Equal(t, []string{"name", "count"}, schema.Required)
+	assert.Equal(t, 3, *schema.Properties["name"].MaxLength)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/schema?Action=Missing&Version=task-common", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 不在 Models 中的模块不能访问
+	web.conf.Models = []string{"other"}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/schema?Action=Validate&Version=task-common", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestWriteOpenApi(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Echo)
+	web := Load(&WebConf{OpenApiPath: "/openapi.json"}).BindController(c)
+	handler := &commonHandler{web: web}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Action=Echo")
+
+	// 不在 Models 中的模块不出现在文档中
+	web.conf.Models = []string{"other"}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Action=Echo")
+}
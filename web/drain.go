@@ -0,0 +1,72 @@
+package web
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/logger"
+)
+
+// 处理中的接口请求，排空阶段不再下发新的接口请求
+// 状态接口、接口文档以及 beforeDispatch 处理的请求不受影响
+type dispatchState struct {
+	mu       sync.Mutex
+	running  int
+	draining bool
+	idle     chan struct{} // 没有处理中的请求时关闭
+}
+
+// 开始下发一个接口请求，排空阶段返回false
+func (d *dispatchState) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	if d.running == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.running++
+	return true
+}
+
+// 接口请求处理结束
+func (d *dispatchState) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running--
+	if d.running == 0 {
+		close(d.idle)
+	}
+}
+
+// Pending 处理中的接口请求数，排空阶段的状态接口据此显示进度
+func (web *webServer) Pending() int {
+	web.dispatching.mu.Lock()
+	defer web.dispatching.mu.Unlock()
+	return web.dispatching.running
+}
+
+// Drain 不再下发新的接口请求，等待处理中的请求结束，ctx到期时返回 DEADLINE_EXCEEDED 错误
+// StartUp 时加入全局资源管理的排空阶段
+func (web *webServer) Drain(ctx context.Context) error {
+	d := &web.dispatching
+	d.mu.Lock()
+	d.draining = true
+	idle, running := d.idle, d.running
+	d.mu.Unlock()
+
+	logger.Info("[Application drain] Web server [:%s] stop dispatching, waiting for %d requests", web.conf.Port, running)
+	if running == 0 {
+		return nil
+	}
+	select {
+	case <-idle:
+		logger.Info("[Application drain] Web server [:%s] all requests finished", web.conf.Port)
+		return nil
+	case <-ctx.Done():
+		logger.Warn("[Application drain] Web server [:%s] drain timeout, %d requests still running", web.conf.Port, web.Pending())
+		return e.NewApiError(e.DEADLINE_EXCEEDED, "drain timeout with running requests", ctx.Err())
+	}
+}
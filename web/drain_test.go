@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zoxu0928/task-common/controller"
+	"github.com/Zoxu0928/task-common/global"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	blockStarted = make(chan struct{})
+	blockRelease = make(chan struct{})
+)
+
+func Block(ctx context.Context, req *echoRequest) (*echoResponse, error) {
+	close(blockStarted)
+	<-blockRelease
+	return &echoResponse{Name: req.Name}, nil
+}
+
+func TestWebServer_Drain(t *testing.T) {
+	c := controller.NewController(false)
+	c.AddController(Block)
+	web := Load(&WebConf{Port: "0", StatusPath: "/status"}).BindController(c)
+	web.StartUp()
+	defer web.Close()
+	handler := &commonHandler{web: web}
+
+	// 启动时加入全局资源管理的排空阶段
+	drainers := global.DefaultResourceManager.Status().Drainers
+	assert.Equal(t, global.DrainerStatus{Name: "webServer"}, drainers[len(drainers)-1])
+
+	blocked := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		handler.ServeHTTP(blocked, httptest.NewRequest(http.MethodGet, "/?Action=Block&Version=task-common&Name=abc", nil))
+	}()
+	<-blockStarted
+	drainers = global.DefaultResourceManager.Status().Drainers
+	assert.Equal(t, 1, drainers[len(drainers)-1].Pending)
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- web.Drain(context.Background())
+	}()
+	for !draining(web) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 排空阶段不再下发新的接口请求，状态接口仍可访问
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?Action=Block&Version=task-common&Name=abc", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "UNAVAILABLE")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 处理中的请求结束后排空完成
+	select {
+	case <-drained:
+		t.Fatal("drain returned with a running request")
+	default:
+	}
+	close(blockRelease)
+	<-served
+	assert.Equal(t, http.StatusOK, blocked.Code)
+	assert.Nil(t, <-drained)
+	assert.Equal(t, 0, web.Pending())
+}
+
+func TestWebServer_DrainTimeout(t *testing.T) {
+	web := Load(&WebConf{})
+	assert.True(t, web.dispatching.begin())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, web.Drain(ctx))
+	assert.False(t, web.dispatching.begin())
+
+	web.dispatching.end()
+	assert.Nil(t, web.Drain(context.Background()))
+}
+
+func draining(web *webServer) bool {
+	web.dispatching.mu.Lock()
+	defer web.dispatching.mu.Unlock()
+	return web.dispatching.draining
+}
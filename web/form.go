@@ -0,0 +1,115 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/e"
+)
+
+var fileHeaderType = reflect.TypeOf(new(multipart.FileHeader))
+var fileHeadersType = reflect.TypeOf([]*multipart.FileHeader{})
+
+// 是否是表单请求
+func isFormRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return strings.Index(contentType, "application/x-www-form-urlencoded") > -1 ||
+		strings.Index(contentType, "multipart/form-data") > -1
+}
+
+// 解析表单请求，表单中的参数合并到url参数中，之后按GET参数的规则封装，同名参数优先使用表单中的值
+// 请求体超过 MaxFormSize 时返回错误，multipart中超过 MaxFormMemory 的文件写入临时文件，请求结束后由 net/http 删除
+func (h *commonHandler) parseForm(w http.ResponseWriter, r *http.Request, ctx *ReqContext) error {
+	maxSize, maxMemory := h.web.conf.MaxFormSize, h.web.conf.MaxFormMemory
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+
+	var values map[string][]string
+	if strings.Index(r.Header.Get("Content-Type"), "multipart/form-data") > -1 {
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			return formError(err, maxSize)
+		}
+		values = r.MultipartForm.Value
+		ctx.files = r.MultipartForm.File
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return formError(err, maxSize)
+		}
+		values = r.PostForm
+	}
+
+	if ctx.params == nil {
+		ctx.params = make(map[string][]string)
+	}
+	for k, v := range values {
+		ctx.params[k] = append(append([]string{}, v...), ctx.params[k]...)
+	}
+	return nil
+}
+
+// http.MaxBytesReader 超过大小时的错误信息，go1.19之前没有 http.MaxBytesError 类型
+const errBodyTooLarge = "http: request body too large"
+
+func formError(err error, maxSize int64) error {
+	if isBodyTooLarge(err) {
+		return e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Request body too large. Limit is %d bytes.", maxSize), err)
+	}
+	return e.NewApiError(e.INVALID_ARGUMENT, "Invalid form body.", err)
+}
+
+// 是否是请求体超过大小限制的错误，multipart解析时错误可能被包装
+func isBodyTooLarge(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err.Error() == errBodyTooLarge {
+			return true
+		}
+	}
+	return false
+}
+
+// 上传文件的字段设置，支持 *multipart.FileHeader 和 []*multipart.FileHeader
+// 多个文件可以使用同一个名称上传，也可以按 Name.1、Name.2 的方式上传
+func (h *commonHandler) setFile(typeName, packageName, anonymousName string, ctx *ReqContext, field *reflect.Value) bool {
+
+	// 获得字段名称
+	fieldName := h.getFieldName(typeName, packageName)
+
+	// 如果是字段是super中匿名继承下来的，去掉匿名部分
+	if anonymousName != "" {
+		fieldName = strings.Replace(fieldName, anonymousName, "", 1)
+	}
+
+	files := ctx.getFiles(fieldName)
+	if len(files) == 0 {
+		for i := 0; i < MAX_PARAM_LIST_LEN; i++ {
+			items := ctx.getFiles(fmt.Sprintf("%s.%d", fieldName, i+1))
+			if len(items) == 0 {
+				break
+			}
+			files = append(files, items[0])
+		}
+	}
+	if len(files) == 0 {
+		return false
+	}
+
+	if field.Type() == fileHeaderType {
+		field.Set(reflect.ValueOf(files[0]))
+	} else {
+		field.Set(reflect.ValueOf(files))
+	}
+	return true
+}
+
+// 上下文中获得上传的文件，名称不区分大小写
+func (ctx *ReqContext) getFiles(key string) []*multipart.FileHeader {
+	for k, v := range ctx.files {
+		if strings.EqualFold(key, k) {
+			return v
+		}
+	}
+	return nil
+}
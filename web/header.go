@@ -0,0 +1,94 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/e"
+)
+
+// 请求参数中从请求头和cookie取值的标签，例如：
+//
+//	type Request struct {
+//		Account string `header:"X-Jcloud-Pin"`
+//		Session string `cookie:"session_id"`
+//	}
+const (
+	TAG_HEADER = "header"
+	TAG_COOKIE = "cookie"
+)
+
+// 按字段上的 header、cookie 标签从请求中取值，匿名的通用参数和嵌套struct中的字段同样处理
+// 请求中没有对应的值时，保留url或body中的参数
+func (h *commonHandler) createHeaderArg(r *http.Request, arg reflect.Value) (bool, error) {
+
+	obj_changed := false
+
+	for i := 0; i < arg.NumField(); i++ {
+		f := arg.Field(i)
+		afType := arg.Type().Field(i)
+		if afType.PkgPath != "" && !afType.Anonymous {
+			continue
+		}
+
+		// 带标签的字段
+		name, val := "", ""
+		if key := afType.Tag.Get(TAG_HEADER); key != "" {
+			name, val = key, r.Header.Get(key)
+		} else if key := afType.Tag.Get(TAG_COOKIE); key != "" {
+			name = key
+			if cookie, err := r.Cookie(key); err == nil {
+				val = cookie.Value
+			}
+		}
+		if name != "" {
+			if val = strings.TrimSpace(val); val == "" || !f.CanSet() {
+				continue
+			}
+			if err := setStringValue(f, val); err != nil {
+				return obj_changed, e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %s", name, val), err)
+			}
+			obj_changed = true
+			continue
+		}
+
+		// 内部对象，递归
+		ft := afType.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct || ft == timeType || ft == dayType {
+			continue
+		}
+		if f.Kind() != reflect.Ptr {
+			changed, err := h.createHeaderArg(r, f)
+			if err != nil {
+				return obj_changed, err
+			}
+			obj_changed = obj_changed || changed
+			continue
+		}
+
+		// 指针为空时，有字段被赋值才创建对象，引用自身类型的字段不处理
+		obj := f
+		if f.IsNil() {
+			if ft == arg.Type() {
+				continue
+			}
+			obj = reflect.New(ft)
+		}
+		changed, err := h.createHeaderArg(r, obj.Elem())
+		if err != nil {
+			return obj_changed, err
+		}
+		if changed {
+			if f.IsNil() && f.CanSet() {
+				f.Set(obj)
+			}
+			obj_changed = true
+		}
+	}
+	return obj_changed, nil
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Zoxu0928/task-common/api/openapi"
+	"github.com/Zoxu0928/task-common/controller"
+)
+
+// 输出当前服务的OpenAPI接口文档
+// 文档根据请求时已注册的接口生成，绑定了controller时只包含绑定的接口，配置了 Models 时只包含其中的模块
+func (h *commonHandler) writeOpenApi(w http.ResponseWriter) {
+	var c controller.Controller = controller.GetDefaultController()
+	if h.web.bindController != nil {
+		c = h.web.bindController
+	}
+	info := openapi.Info{Title: h.web.conf.Name, Version: DEFAULT_VERSION}
+	if info.Title == "" {
+		info.Title = "api"
+	}
+	body, err := json.Marshal(openapi.Generate(c, info, h.web.conf.RootPath, h.web.conf.Models))
+	if err != nil {
+		h.handlerError(w, err, &ReqContext{}, true)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
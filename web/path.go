@@ -0,0 +1,102 @@
+package web
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/api/restful"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/tools/json/ffjson"
+)
+
+// restful路径上的参数按请求结构中字段的类型赋值，字段与json一致按名称不区分大小写匹配
+// 类型转换失败时返回 INVALID_ARGUMENT，错误详情中记录参数名称
+func (h *commonHandler) createPathArg(arg reflect.Value, params map[string]interface{}) error {
+	stru := arg
+	if stru.Kind() == reflect.Ptr {
+		stru = stru.Elem()
+	}
+	if stru.Kind() != reflect.Struct {
+		return nil
+	}
+	for name, value := range params {
+		if value == nil {
+			continue
+		}
+		f, ok := findPathField(stru, name)
+		if !ok {
+			continue
+		}
+		if err := setPathValue(f, value); err != nil {
+			apiErr := e.NewApiError(e.INVALID_ARGUMENT, fmt.Sprintf("Malformed %s %v", name, value), err)
+			apiErr.AddDetail(restful.K_DETAIL_FIELD, name)
+			return apiErr
+		}
+	}
+	return nil
+}
+
+// 为参数对应的字段赋值
+// 字符串、数字、布尔类型的值按字段类型转换，其它类型按json转换
+func setPathValue(f reflect.Value, value interface{}) error {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return setStringValue(f, rv.String())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return setStringValue(f, fmt.Sprint(value))
+	default:
+		data, err := ffjson.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return ffjson.Unmarshal(data, f.Addr().Interface())
+	}
+}
+
+// 按json的规则查找字段，匿名struct中的字段可以直接匹配，找到时为空的匿名struct指针会被初始化
+func findPathField(stru reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < stru.NumField(); i++ {
+		afType := stru.Type().Field(i)
+		if afType.PkgPath != "" && !afType.Anonymous {
+			continue
+		}
+		tag := afType.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		key := strings.Split(tag, ",")[0]
+
+		f := stru.Field(i)
+		ft := afType.Type
+		if afType.Anonymous && key == "" && (ft.Kind() == reflect.Struct || ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct) {
+			if ft.Kind() == reflect.Struct {
+				if v, ok := findPathField(f, name); ok {
+					return v, true
+				}
+				continue
+			}
+			obj := f
+			if f.IsNil() {
+				obj = reflect.New(ft.Elem())
+			}
+			if v, ok := findPathField(obj.Elem(), name); ok {
+				if f.IsNil() && f.CanSet() {
+					f.Set(obj)
+				}
+				return v, true
+			}
+			continue
+		}
+
+		if key == "" {
+			key = afType.Name
+		}
+		if strings.EqualFold(key, name) && f.CanSet() {
+			return f, true
+		}
+	}
+	return reflect.Value{}, false
+}
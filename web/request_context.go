@@ -1,13 +1,17 @@
 package web
 
 import (
+	"context"
+	"github.com/Zoxu0928/task-common/api"
 	"github.com/Zoxu0928/task-common/basic"
 	"github.com/Zoxu0928/task-common/e"
 	"github.com/Zoxu0928/task-common/tools"
+	"mime/multipart"
 	"net/http"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -37,20 +41,23 @@ var timeType = reflect.TypeOf(new(basic.TimeStandard)).Elem()
 var dayType = reflect.TypeOf(new(basic.Day)).Elem()
 
 // 请求上下文，在当前请求中进行传递
+// 实现了 context.Context，客户端断开连接或超过 WriteTimeout 时取消，可以直接传给Controller
 type ReqContext struct {
-	requestId    string                 //当前请求的唯一ID
-	request      interface{}            //对应Controller入参
-	response     interface{}            //对应Controller出参
-	superRequest interface{}            //对应Controller入参中的通用参数
-	model        string                 //对应Controller所在模块
-	action       string                 //对应Controller名称
-	version      string                 //对应Controller版本
-	params       map[string][]string    //对应Get请求Url路径上的参数
-	attr         map[string]interface{} //可以设置一些自定义缓存属性，在当前请求中一直有效
-	method       string                 //对应http请求的类型：GET、POST
-	status       int                    //http响应码
-	r            *http.Request          //http请求
-	format       string                 //格式化响应Response，支持json、xml
+	ctx          context.Context                    //由http请求的Context派生
+	requestId    string                             //当前请求的唯一ID
+	request      interface{}                        //对应Controller入参
+	response     interface{}                        //对应Controller出参
+	superRequest interface{}                        //对应Controller入参中的通用参数
+	model        string                             //对应Controller所在模块
+	action       string                             //对应Controller名称
+	version      string                             //对应Controller版本
+	params       map[string][]string                //对应Get请求Url路径上的参数
+	files        map[string][]*multipart.FileHeader //对应multipart表单中上传的文件
+	attr         map[string]interface{}             //可以设置一些自定义缓存属性，在当前请求中一直有效
+	method       string                             //对应http请求的类型：GET、POST
+	status       int                                //http响应码
+	r            *http.Request                      //http请求
+	format       string                             //格式化响应Response，支持json、xml
 	mu           sync.RWMutex
 }
 
@@ -120,6 +127,41 @@ func (ctx *ReqContext) GetSuperRequest() interface{} {
 	return ctx.superRequest
 }
 
+// 替换上下文的父Context，一般用 context.WithXXX(ctx.GetContext()) 派生后设置
+func (ctx *ReqContext) SetContext(c context.Context) {
+	ctx.ctx = c
+}
+func (ctx *ReqContext) GetContext() context.Context {
+	if ctx.ctx == nil {
+		return context.Background()
+	}
+	return ctx.ctx
+}
+
+// 实现 context.Context
+func (ctx *ReqContext) Deadline() (deadline time.Time, ok bool) {
+	return ctx.GetContext().Deadline()
+}
+func (ctx *ReqContext) Done() <-chan struct{} {
+	return ctx.GetContext().Done()
+}
+func (ctx *ReqContext) Err() error {
+	return ctx.GetContext().Err()
+}
+
+// 支持 api.RequestIdKey 和 api.TenantIdKey，用户ID取自通用请求参数
+func (ctx *ReqContext) Value(key interface{}) interface{} {
+	switch key {
+	case api.RequestIdKey:
+		return ctx.requestId
+	case api.TenantIdKey:
+		if req, ok := ctx.superRequest.(interface{ GetTenantId() string }); ok && req.GetTenantId() != "" {
+			return req.GetTenantId()
+		}
+	}
+	return ctx.GetContext().Value(key)
+}
+
 // 获得方法名称
 func (ctx *ReqContext) getActionName() string {
 	return ctx.model + "." + ctx.action
@@ -15,7 +15,11 @@ import (
 // 捕获未知错误
 func (h *commonHandler) onError(w http.ResponseWriter, ctx *ReqContext) {
 	if r := recover(); r != nil {
-		logger.Error("%s - 发生未知错误：%s\n%s", ctx.requestId, r, string(debug.Stack()))
+		stack := debug.Stack()
+		if p, ok := r.(*invokePanic); ok {
+			r, stack = p.value, p.stack
+		}
+		logger.Error("%s - 发生未知错误：%s\n%s", ctx.requestId, r, string(stack))
 		var err error
 		switch x := r.(type) {
 		case string:
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/controller"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/validator"
+)
+
+// 输出接口入参校验规则的JSON Schema，前端表单可以直接使用
+// 请求参数与接口调用一致：Action、Version，controller使用model时需要传入Model，配置了 Models 时同样只能访问其中的模块
+func (h *commonHandler) writeSchema(w http.ResponseWriter, r *http.Request) {
+	ctx := &ReqContext{}
+	action, version := queryValue(r, REQ_ACTION), queryValue(r, REQ_VERSION)
+	if version == "" {
+		version = DEFAULT_VERSION
+	}
+	if action == "" {
+		h.handlerError(w, e.NewApiError(e.INVALID_ARGUMENT, "Invalid action name.", nil), ctx, false)
+		return
+	}
+
+	var c controller.Controller = controller.GetDefaultController()
+	if h.web.bindController != nil {
+		c = h.web.bindController
+	}
+	ctx.model, ctx.action, ctx.version = queryValue(r, "model"), strings.Title(action), version
+	method := c.GetController(ctx.model+"."+ctx.action, version)
+	arg := requestArgOf(method)
+	if arg == nil {
+		h.handlerError(w, e.NewApiError(e.NOT_FOUND, fmt.Sprintf("No such api. action=%s version=%s", action, version), nil), ctx, false)
+		return
+	}
+
+	// 与接口调用一致，只能访问此httpServer配置的模块
+	if !h.useModel() && ctx.model == "" {
+		ctx.model = method.GetPkgName()
+	}
+	if !h.hasPermission(ctx) {
+		h.handlerError(w, e.NewApiError(e.PERMISSION_DENIED, "Access denied.", nil), ctx, false)
+		return
+	}
+
+	body, err := json.Marshal(validator.JsonSchema(reflect.New(arg).Interface()))
+	if err != nil {
+		h.handlerError(w, err, ctx, true)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// 接口的struct入参类型
+func requestArgOf(method *basic.Method) reflect.Type {
+	if method == nil || len(method.GetArgs()) == 0 {
+		return nil
+	}
+	arg := method.GetArgs()[0]
+	if arg.Kind() == reflect.Ptr {
+		arg = arg.Elem()
+	}
+	if arg.Kind() != reflect.Struct {
+		return nil
+	}
+	return arg
+}
+
+// url中的参数，参数名称首字母可以大写
+func queryValue(r *http.Request, key string) string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(strings.Title(key))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Zoxu0928/task-common/basic"
 	"github.com/Zoxu0928/task-common/controller"
 	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/global"
 	"github.com/Zoxu0928/task-common/interceptor"
 	"github.com/Zoxu0928/task-common/logger"
 	"net/http"
@@ -28,12 +29,13 @@ import (
 //      就是业务代码中的某某Service中的首字母大写的Func
 // 3. interceptor - 拦截器
 // 4. validator - 参数验证
+//      validator.NewPointCut 创建内置的参数校验拦截器，在执行Controller之前校验入参，并返回所有未通过校验的字段
 
 // Web框架对请求的处理逻辑大概如下：
 // 1. 接收 Get请求、Post json请求、restful请求
 // 2. 实例化RequestContext请求上下文，里面可以获取一些通用属性，可以设置一些缓存信息
 // 3. 定位Controller，实例化Controller的入参
-// 4. 将http请求的参数转换为Controller的入参，只能转换正常参数，Header中的无法处理
+// 4. 将http请求的参数转换为Controller的入参，Header和Cookie中的参数通过字段上的 header、cookie 标签转换
 // 5. 执行拦截器链（BeforeFunc），在拦截器中可以处理：限流、日志、参数校验等逻辑
 // 6. 执行Controller
 // 7. 反向执行拦截器链（AfterFunc）
@@ -46,9 +48,11 @@ import (
 
 // 默认配置
 const (
-	DEFAULT_HTTP_PORT          = "8080"
-	DEFAULT_HTTP_READ_TIMEOUT  = 30 * time.Second
-	DEFAULT_HTTP_WRITE_TIMEOUT = 30 * time.Second
+	DEFAULT_HTTP_PORT            = "8080"
+	DEFAULT_HTTP_READ_TIMEOUT    = 30 * time.Second
+	DEFAULT_HTTP_WRITE_TIMEOUT   = 30 * time.Second
+	DEFAULT_HTTP_MAX_FORM_SIZE   = 32 << 20 // 表单请求体最大32M
+	DEFAULT_HTTP_MAX_FORM_MEMORY = 4 << 20  // 上传文件超过4M时写入临时文件
 )
 
 // 可以向webServer中注册一些函数，以便在请求执行之前，或向客户端响应之前做一些自定义的处理
@@ -56,7 +60,7 @@ const (
 // Web框架收到http请求后，会第一时间进行回调。如果回调返回true，Web框架会结束请求。
 type beforeDispatchCallbackFunc func(w http.ResponseWriter, r *http.Request) bool
 
-// Web框架处理完http参数后，会回调一次，在回调中可以处理一些非常规的参数，比如Header中的自定义参数。
+// Web框架处理完http参数后，会回调一次，在回调中可以处理一些非常规的参数，比如需要计算或校验的Header参数。
 type handlerRequestFunc func(ctx *ReqContext, restAction *restful.ActionMeta, w http.ResponseWriter, r *http.Request) e.ApiError
 
 // Web框架在输出Response之前，会回调一次，在回调中可以实现一些自定义逻辑
@@ -139,6 +143,9 @@ type webServer struct {
 	// 如果没有设置，Web框架会使用默认的全局路由定位Controller
 	// 如果设置了，Web框架只会在绑定在自己端口下的路由中查找Controller
 	bindController controller.Controller
+
+	// 处理中的接口请求，排空阶段据此等待
+	dispatching dispatchState
 }
 
 // 加载配置文件并返回web实例
@@ -155,12 +162,20 @@ func Load(conf *WebConf) *webServer {
 
 // web配置
 type WebConf struct {
-	Name         string         `yaml:"http.name" toml:"name"` // 可空
-	Port         string         `yaml:"http.port" toml:"port"`
-	ReadTimeout  basic.Duration `yaml:"http.readTimeout" toml:"read_timeout"`
-	WriteTimeout basic.Duration `yaml:"http.writeTimeout" toml:"write_timeout"`
-	RootPath     string         `yaml:"http.rootPath" toml:"root_path"` // 可空
-	Models       []string       `yaml:"http.models" toml:"models"`      // 可空，如果不为空，说明此httpServer只能访问配置的模块
+	Name          string         `yaml:"http.name" toml:"name"` // 可空
+	Port          string         `yaml:"http.port" toml:"port"`
+	ReadTimeout   basic.Duration `yaml:"http.readTimeout" toml:"read_timeout"`
+	WriteTimeout  basic.Duration `yaml:"http.writeTimeout" toml:"write_timeout"`
+	RootPath      string         `yaml:"http.rootPath" toml:"root_path"`            // 可空
+	Models        []string       `yaml:"http.models" toml:"models"`                 // 可空，如果不为空，说明此httpServer只能访问配置的模块
+	StatusPath    string         `yaml:"http.statusPath" toml:"status_path"`        // 可空，服务状态接口的路径，例如 /status
+	OpenApiPath   string         `yaml:"http.openApiPath" toml:"openapi_path"`      // 可空，OpenAPI接口文档的路径，例如 /openapi.json
+	SchemaPath    string         `yaml:"http.schemaPath" toml:"schema_path"`        // 可空，接口入参校验规则的JSON Schema路径，例如 /schema?Action=CreateInstance&Version=v1
+	MaxFormSize   int64          `yaml:"http.maxFormSize" toml:"max_form_size"`     // 可空，表单请求体的最大字节数
+	MaxFormMemory int64          `yaml:"http.maxFormMemory" toml:"max_form_memory"` // 可空，multipart表单在内存中保存的最大字节数，超过的文件写入临时文件
+	// 可空，接口执行的超时时间，key为 Method.GetFullName()，例如 github.com/xx/controller/v1/vm.CreateInstance
+	// 没有 context.Context 入参的接口无法感知取消，超时后仍会继续执行
+	Timeouts map[string]basic.Duration `yaml:"http.timeouts" toml:"timeouts"`
 }
 
 func (this *webServer) GetConf() *WebConf {
@@ -178,6 +193,25 @@ func (this *webServer) SetWriteTimeout(v basic.Duration) *webServer {
 	this.conf.WriteTimeout = v
 	return this
 }
+func (this *webServer) SetStatusPath(path string) *webServer {
+	this.conf.StatusPath = path
+	return this
+}
+func (this *webServer) SetOpenApiPath(path string) *webServer {
+	this.conf.OpenApiPath = path
+	return this
+}
+func (this *webServer) SetSchemaPath(path string) *webServer {
+	this.conf.SchemaPath = path
+	return this
+}
+func (this *webServer) SetTimeout(fullName string, timeout time.Duration) *webServer {
+	if this.conf.Timeouts == nil {
+		this.conf.Timeouts = make(map[string]basic.Duration)
+	}
+	this.conf.Timeouts[fullName] = basic.Duration{Duration: timeout}
+	return this
+}
 func (this *webServer) SetSuperRequestType(superRequestType string) *webServer {
 	this.superRequestType = superRequestType
 	return this
@@ -228,6 +262,12 @@ func parseCfg(httpConf *WebConf) {
 	if httpConf.WriteTimeout.Duration <= 0 {
 		httpConf.WriteTimeout.Duration = DEFAULT_HTTP_WRITE_TIMEOUT
 	}
+	if httpConf.MaxFormSize <= 0 {
+		httpConf.MaxFormSize = DEFAULT_HTTP_MAX_FORM_SIZE
+	}
+	if httpConf.MaxFormMemory <= 0 {
+		httpConf.MaxFormMemory = DEFAULT_HTTP_MAX_FORM_MEMORY
+	}
 }
 
 // 核心Handler，所有进入的请求都会经过此handler
@@ -238,12 +278,37 @@ type commonHandler struct {
 // 实现ServeHTTP，代表commonHandler实现了Handler
 func (h *commonHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
+	// 服务状态接口，排空和停机阶段也可以访问
+	if h.web.conf.StatusPath != "" && r.URL.Path == h.web.conf.StatusPath {
+		h.writeStatus(w)
+		return
+	}
+
+	// 接口文档
+	if h.web.conf.OpenApiPath != "" && r.URL.Path == h.web.conf.OpenApiPath {
+		h.writeOpenApi(w)
+		return
+	}
+
+	// 入参校验规则
+	if h.web.conf.SchemaPath != "" && r.URL.Path == h.web.conf.SchemaPath {
+		h.writeSchema(w, r)
+		return
+	}
+
 	// 下发请求之前，预处理一次
 	// 如果预处理的结果为true，说明上方业务层自己处理了该请求，该框架不再处理该请求，流程直接结束，不再处理
 	if h.web.beforeDispatch != nil && h.web.beforeDispatch(w, r) {
 		return
 	}
 
+	// 排空阶段不再下发新的接口请求
+	if !h.web.dispatching.begin() {
+		h.handlerError(w, e.NewApiError(e.UNAVAILABLE, "Service is draining.", nil), &ReqContext{}, false)
+		return
+	}
+	defer h.web.dispatching.end()
+
 	// Api接口请求：下发请求
 	h.dispatchHandler(w, r)
 }
@@ -270,6 +335,9 @@ func (web *webServer) StartUp() {
 		logger.Info("Port %s Support models %s", web.conf.Port, web.conf.Models)
 	}
 
+	// 停机时先排空处理中的请求，再关闭
+	global.DefaultResourceManager.AddDrainer(web)
+
 	// 开始监听
 	go func() {
 		err := web.server.ListenAndServe()
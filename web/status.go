@@ -0,0 +1,23 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Zoxu0928/task-common/global"
+)
+
+// 输出服务状态，包括排空阶段的进度
+// 运行中返回200，排空和停机阶段返回503，负载均衡可以据此摘除流量
+func (h *commonHandler) writeStatus(w http.ResponseWriter) {
+	status := global.DefaultResourceManager.Status()
+	body, _ := json.Marshal(map[string]interface{}{"result": status})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if global.DefaultResourceManager.Serving() {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(body)
+}
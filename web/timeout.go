@@ -0,0 +1,97 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/Zoxu0928/task-common/api/restful"
+	"github.com/Zoxu0928/task-common/basic"
+	"github.com/Zoxu0928/task-common/e"
+	"github.com/Zoxu0928/task-common/logger"
+)
+
+// 接口执行的超时时间，restful注册时指定的优先，其次是配置中按 Method.GetFullName() 指定的
+// 为0时不限制，只受 WriteTimeout 约束
+func (h *commonHandler) timeoutOf(method *basic.Method, restAction *restful.ActionMeta) time.Duration {
+	if restAction != nil && restAction.GetTimeout() > 0 {
+		return restAction.GetTimeout()
+	}
+	if timeout, ok := h.web.conf.Timeouts[method.GetFullName()]; ok {
+		return timeout.Duration
+	}
+	return 0
+}
+
+// 超时调用中目标函数的panic，保留发生panic时的调用栈
+type invokePanic struct {
+	value interface{}
+	stack []byte
+}
+
+// 在超时时间内调用目标函数
+// 超时后返回 DEADLINE_EXCEEDED 错误，目标函数通过 context.Context 入参感知取消，返回值被丢弃
+// 没有 context.Context 入参的目标函数超时后仍会执行到结束，期间仍可能读写入参
+// 目标函数的panic只记录一次：返回之前发生的交给 onError，超时返回之后发生的在协程中记录
+func (h *commonHandler) invokeTimeout(method *basic.Method, args []reflect.Value, ctx *ReqContext, timeout time.Duration) ([]reflect.Value, error) {
+	invokeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		outs  []reflect.Value
+		panic *invokePanic
+	}
+	// 目标函数结束和调用方超时返回只有一方能够成功设置
+	const (
+		invokeRunning int32 = iota
+		invokeFinished
+		invokeAbandoned
+	)
+	state := invokeRunning
+	done := make(chan result, 1)
+	go func() {
+		res := result{}
+		defer func() {
+			if r := recover(); r != nil {
+				res.panic = &invokePanic{value: r, stack: debug.Stack()}
+			}
+			if atomic.CompareAndSwapInt32(&state, invokeRunning, invokeFinished) {
+				done <- res
+				return
+			}
+			if res.panic != nil {
+				logger.Error("%s - 超时后发生未知错误：%s\n%s", ctx.requestId, res.panic.value, string(res.panic.stack))
+			}
+		}()
+		res.outs = method.InvokeContext(invokeCtx, args)
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-invokeCtx.Done():
+		if !atomic.CompareAndSwapInt32(&state, invokeRunning, invokeAbandoned) {
+			// 目标函数恰好在超时时结束，其panic仍交给 onError
+			res = <-done
+		}
+	}
+
+	// 目标函数的panic交给请求的统一错误处理
+	if res.panic != nil {
+		panic(res.panic)
+	}
+
+	// 超时或客户端断开之后结束的目标函数，返回值被丢弃
+	if err := invokeCtx.Err(); err != nil {
+		// 客户端断开连接
+		if err == context.Canceled {
+			return nil, e.NewApiError(e.CANCELLED, "Request canceled.", err)
+		}
+		logger.Warn("%s %s timed out after %s", ctx.requestId, method.GetFullName(), timeout)
+		return nil, e.NewApiError(e.DEADLINE_EXCEEDED, fmt.Sprintf("Request timed out after %s.", timeout), err)
+	}
+	return res.outs, nil
+}